@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bkpfile/internal/bkpfile"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	backupsNote        string
+	backupsConcurrency int
+	backupsJSON        bool
+)
+
+var backupsCmd = &cobra.Command{
+	Use:   "backups <file-path>...",
+	Short: "Back up multiple files concurrently, reporting progress as it goes",
+	Long: `Backs up every given file, fanning the work across --concurrency
+workers (runtime.NumCPU() by default; 1 runs files serially, the same as
+running bkpfile once per file). Each file goes through the same
+identical-backup short-circuit and disk-full handling as a single-file
+backup, and one file's failure doesn't stop the rest of the batch.
+
+With --json, progress is emitted as newline-delimited JSON records
+("status" per file event, a final "summary") instead of human-readable
+text, so the run can be scripted or fed into another tool.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, diags, err := bkpfile.LoadConfig(".")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		printWarningDiagnostics(bkpfile.NewOutputFormatter(cfg), diags)
+
+		obs := newBatchObserver(cmd.OutOrStdout(), backupsJSON)
+		cfg.Observer = obs
+
+		results, err := bkpfile.CreateBackups(cfg, args, backupsNote, dryRun, backupsConcurrency)
+		if err != nil {
+			return fmt.Errorf("failed to run backup batch: %w", err)
+		}
+		obs.summary(results)
+
+		failed := 0
+		for _, result := range results {
+			if result.Err != nil {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d backup(s) failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+func init() {
+	backupsCmd.Flags().StringVar(&backupsNote, "note", "", "Note attached to every backup in the batch")
+	backupsCmd.Flags().IntVar(&backupsConcurrency, "concurrency", 0, "Number of files to back up at once (default runtime.NumCPU())")
+	backupsCmd.Flags().BoolVar(&backupsJSON, "json", false, "Emit newline-delimited JSON status/summary records instead of text")
+	rootCmd.AddCommand(backupsCmd)
+}
+
+// batchRecord is one newline-delimited JSON record emitted by
+// batchObserver in --json mode.
+type batchRecord struct {
+	Type              string  `json:"type"`
+	Path              string  `json:"path,omitempty"`
+	Bytes             int64   `json:"bytes,omitempty"`
+	Error             string  `json:"error,omitempty"`
+	ElapsedSeconds    float64 `json:"elapsed_seconds"`
+	ThroughputBytesPS float64 `json:"throughput_bytes_per_sec,omitempty"`
+	ETASeconds        float64 `json:"eta_seconds,omitempty"`
+	Total             int     `json:"total,omitempty"`
+	Succeeded         int     `json:"succeeded,omitempty"`
+	Failed            int     `json:"failed,omitempty"`
+}
+
+// batchObserver drives the --json and plain-text progress output for the
+// backups command, tracking aggregate bytes/throughput across the whole
+// batch so it can report an ETA alongside each file's status.
+type batchObserver struct {
+	out     *json.Encoder
+	asJSON  bool
+	started time.Time
+
+	mu         sync.Mutex
+	totalBytes int64
+	doneBytes  int64
+}
+
+func newBatchObserver(out io.Writer, asJSON bool) *batchObserver {
+	return &batchObserver{out: json.NewEncoder(out), asJSON: asJSON, started: time.Now()}
+}
+
+func (o *batchObserver) emit(r batchRecord) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	r.ElapsedSeconds = time.Since(o.started).Seconds()
+	if o.asJSON {
+		o.out.Encode(r)
+		return
+	}
+	switch r.Type {
+	case "status":
+		if r.Error != "" {
+			fmt.Printf("%s: error: %s\n", r.Path, r.Error)
+		} else {
+			fmt.Printf("%s: done\n", r.Path)
+		}
+	case "summary":
+		fmt.Printf("%d backed up, %d failed\n", r.Succeeded, r.Failed)
+	}
+}
+
+func (o *batchObserver) OnStart(path string, size int64) {
+	atomic.AddInt64(&o.totalBytes, size)
+}
+
+func (o *batchObserver) OnProgress(path string, bytesDone int64) {
+	done := atomic.AddInt64(&o.doneBytes, bytesDone)
+
+	elapsed := time.Since(o.started).Seconds()
+	var throughput, eta float64
+	if elapsed > 0 {
+		throughput = float64(done) / elapsed
+	}
+	total := atomic.LoadInt64(&o.totalBytes)
+	if throughput > 0 && total > done {
+		eta = float64(total-done) / throughput
+	}
+
+	o.emit(batchRecord{
+		Type:              "status",
+		Path:              path,
+		Bytes:             bytesDone,
+		ThroughputBytesPS: throughput,
+		ETASeconds:        eta,
+	})
+}
+
+func (o *batchObserver) OnComplete(path string, result bkpfile.BackupResult) {}
+
+func (o *batchObserver) OnError(path string, err error) {
+	o.emit(batchRecord{Type: "status", Path: path, Error: err.Error()})
+}
+
+func (o *batchObserver) summary(results []bkpfile.BackupResult) {
+	succeeded, failed := 0, 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+		} else {
+			succeeded++
+		}
+	}
+	o.emit(batchRecord{Type: "summary", Total: len(results), Succeeded: succeeded, Failed: failed})
+}
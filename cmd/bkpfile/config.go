@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"bkpfile/internal/bkpfile"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	configWritePath    string
+	configInit         bool
+	configRestoreForce bool
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect, export, or bootstrap the bkpfile configuration",
+	Long: `Inspect, export, or bootstrap the bkpfile configuration.
+
+Two pairs of subcommands snapshot the configuration for different
+purposes:
+
+  export/import  captures only the resolved config values (config.yml
+                  + manifest.yml). Use this to move your effective
+                  settings to another machine or check them into
+                  version control.
+  backup/restore  additionally copies every raw config source file
+                  found via the search path, with checksums, into the
+                  bundle. Use this to preserve exactly which files
+                  produced the effective config and put them back
+                  byte-for-byte, e.g. before editing them or migrating
+                  a machine.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if configWritePath == "" && !configInit {
+			return cmd.Help()
+		}
+
+		cfg, diags, err := bkpfile.LoadConfig(".")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		printWarningDiagnostics(bkpfile.NewOutputFormatter(cfg), diags)
+
+		if err := cfg.SafeWriteConfig(configWritePath); err != nil {
+			return err
+		}
+
+		resolvedPath, err := bkpfile.ResolveConfigPath(configWritePath)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Wrote configuration to %s\n", resolvedPath)
+		return nil
+	},
+}
+
+var configExportCmd = &cobra.Command{
+	Use:   "export <dir>",
+	Short: "Write the computed configuration to a self-contained bundle",
+	Long: `Resolves every configuration value the same way --config does and
+writes it into <dir> as config.yml, alongside a manifest.yml recording
+where each value came from. The target directory must not already
+contain a config export.
+
+This only captures the resolved values, not the source files that
+produced them; use "bkpfile config backup" instead if you need the raw
+config files (with checksums) preserved too.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := args[0]
+
+		manifestPath := filepath.Join(dir, "manifest.yml")
+		if _, err := os.Stat(manifestPath); err == nil {
+			return fmt.Errorf("%s already contains a config export", dir)
+		}
+
+		configValues, diags, err := bkpfile.ResolveConfigValues()
+		printWarningDiagnostics(bkpfile.NewOutputFormatter(bkpfile.DefaultConfig()), diags)
+		if err != nil {
+			return err
+		}
+
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create export directory: %w", err)
+		}
+
+		cfg, _, err := bkpfile.LoadConfig(".")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		configData, err := yaml.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "config.yml"), configData, 0644); err != nil {
+			return fmt.Errorf("failed to write config.yml: %w", err)
+		}
+
+		manifestData, err := yaml.Marshal(configValues)
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest: %w", err)
+		}
+		if err := os.WriteFile(manifestPath, manifestData, 0644); err != nil {
+			return fmt.Errorf("failed to write manifest.yml: %w", err)
+		}
+
+		fmt.Printf("Exported configuration to %s\n", dir)
+		return nil
+	},
+}
+
+var configImportCmd = &cobra.Command{
+	Use:   "import <dir>",
+	Short: "Install a config.yml exported by `bkpfile config export`",
+	Long: `Reads config.yml from <dir> and writes it to the first path in
+the configuration search order, so it takes effect on the next run.
+
+This installs a bundle written by "bkpfile config export"; a bundle
+written by "bkpfile config backup" instead requires
+"bkpfile config restore".`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := args[0]
+
+		data, err := os.ReadFile(filepath.Join(dir, "config.yml"))
+		if err != nil {
+			return fmt.Errorf("failed to read config.yml from %s: %w", dir, err)
+		}
+
+		var probe map[string]interface{}
+		if err := yaml.Unmarshal(data, &probe); err != nil {
+			return fmt.Errorf("%s/config.yml is not valid YAML: %w", dir, err)
+		}
+
+		searchPaths := bkpfile.GetConfigSearchPath()
+		if len(searchPaths) == 0 {
+			return fmt.Errorf("no configuration search path is available to import into")
+		}
+		destPath := searchPaths[0]
+
+		if err := os.WriteFile(destPath, data, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", destPath, err)
+		}
+
+		fmt.Printf("Imported configuration into %s\n", destPath)
+		return nil
+	},
+}
+
+var configBackupCmd = &cobra.Command{
+	Use:   "backup <dir>",
+	Short: "Snapshot the effective configuration and its sources to a directory",
+	Long: `Writes the merged configuration, a copy of every file found via the
+configuration search path, and a manifest recording their checksums and
+file info into <dir>. <dir> must not already exist, but its parent must.
+The resulting bundle can be moved to another machine and restored with
+"bkpfile config restore".
+
+This preserves the raw source files themselves, not just the values
+bkpfile resolved from them; use "bkpfile config export" instead if you
+only want the effective configuration.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := bkpfile.ConfigBackup(args[0]); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+		fmt.Printf("Backed up configuration to %s\n", args[0])
+		return nil
+	},
+}
+
+var configRestoreCmd = &cobra.Command{
+	Use:   "restore <dir> [target-root]",
+	Short: "Restore a configuration backup created by `bkpfile config backup`",
+	Long: `Reads the manifest from <dir> and rewrites each captured source file
+back to its original path, rooted under [target-root] (default: "/",
+i.e. the original absolute paths). Refuses to overwrite a file that
+already exists at its destination unless --force is given.
+
+This restores a bundle written by "bkpfile config backup"; a bundle
+written by "bkpfile config export" instead requires
+"bkpfile config import".`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := args[0]
+		targetRoot := "/"
+		if len(args) > 1 {
+			targetRoot = args[1]
+		}
+
+		var err error
+		if configRestoreForce {
+			err = bkpfile.ConfigRestore(dir, targetRoot)
+		} else {
+			err = bkpfile.SafeConfigRestore(dir, targetRoot)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore config: %w", err)
+		}
+
+		fmt.Printf("Restored configuration from %s into %s\n", dir, targetRoot)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.Flags().StringVar(&configWritePath, "write", "", "Write the currently effective configuration to the given path (default: first entry in the config search path)")
+	configCmd.Flags().BoolVar(&configInit, "init", false, "Bootstrap a config file at the default config search path")
+
+	configRestoreCmd.Flags().BoolVar(&configRestoreForce, "force", false, "Overwrite files that already exist at the restore destination")
+
+	configCmd.AddCommand(configExportCmd)
+	configCmd.AddCommand(configImportCmd)
+	configCmd.AddCommand(configBackupCmd)
+	configCmd.AddCommand(configRestoreCmd)
+	rootCmd.AddCommand(configCmd)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+
+	"bkpfile/internal/bkpfile"
+
+	"github.com/spf13/cobra"
+)
+
+var daemonDryRun bool
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run bkpfile as a long-lived process scheduling profile backups",
+	Long: `Starts bkpfile as a long-running process that schedules recurring backups
+for each named profile in the loaded configuration. Sending SIGINT or
+SIGTERM shuts the daemon down gracefully, letting any in-flight run
+finish; sending SIGHUP reloads the configuration and adds/removes
+schedules without dropping in-flight backups. With --dry-run, every
+scheduled run reports what it would do without writing any backups,
+which is useful for validating profile cron expressions.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, diags, err := bkpfile.LoadConfig(".")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		printWarningDiagnostics(bkpfile.NewOutputFormatter(cfg), diags)
+
+		d := bkpfile.NewDaemon(".", cfg, bkpfile.DaemonOptions{DryRun: daemonDryRun})
+		return d.Run()
+	},
+}
+
+func init() {
+	daemonCmd.Flags().BoolVar(&daemonDryRun, "dry-run", false, "Report what each scheduled run would do without writing any backups")
+	rootCmd.AddCommand(daemonCmd)
+}
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"bkpfile/internal/bkpfile"
+	"bkpfile/internal/diag"
 
 	"github.com/spf13/cobra"
 )
@@ -21,6 +23,12 @@ var (
 	dryRun bool
 	list   bool
 	config bool
+	prune  bool
+	tags   []string
+
+	// Glob/wildcard source selection flags
+	includePatterns []string
+	excludePatterns []string
 
 	// Root command
 	rootCmd = &cobra.Command{
@@ -32,7 +40,9 @@ var (
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// Handle config flag first (exclusive operation)
 			if config {
-				return bkpfile.DisplayConfig()
+				diags, err := bkpfile.DisplayConfig()
+				printWarningDiagnostics(bkpfile.NewOutputFormatter(bkpfile.DefaultConfig()), diags)
+				return err
 			}
 
 			// Require at least one argument for other operations
@@ -47,7 +57,7 @@ var (
 			}
 
 			// Load configuration
-			cfg, err := bkpfile.LoadConfig(".")
+			cfg, diags, err := bkpfile.LoadConfig(".")
 			if err != nil {
 				// Configuration error should use the config error status code
 				// Use default config to get the status code since loading failed
@@ -56,10 +66,27 @@ var (
 				formatter.PrintError(fmt.Sprintf("failed to load config: %v", err))
 				os.Exit(defaultCfg.StatusConfigError)
 			}
+			printWarningDiagnostics(bkpfile.NewOutputFormatter(cfg), diags)
 
 			// Create formatter with loaded configuration
 			formatter := bkpfile.NewOutputFormatter(cfg)
 
+			if prune {
+				// Prune old backups according to the configured retention policy
+				_, err := bkpfile.PruneBackups(cfg, filePath, dryRun)
+				if backupErr, ok := err.(*bkpfile.BackupError); ok {
+					if backupErr.StatusCode != cfg.StatusPruned {
+						formatter.PrintError(backupErr.Message)
+					}
+					os.Exit(backupErr.StatusCode)
+				}
+				if err != nil {
+					formatter.PrintError(err.Error())
+					os.Exit(cfg.StatusConfigError)
+				}
+				return nil
+			}
+
 			if list {
 				// List backups
 				backups, err := bkpfile.ListBackups(cfg.BackupDirPath, filePath)
@@ -80,8 +107,17 @@ var (
 				return nil
 			}
 
+			if isGlobPattern(filePath) || len(includePatterns) > 0 || len(excludePatterns) > 0 {
+				return runGlobBackup(formatter, cfg, filePath, note)
+			}
+
 			// Create backup
-			err = bkpfile.CreateBackup(cfg, filePath, note, dryRun)
+			tagMap, err := parseTags(tags)
+			if err != nil {
+				formatter.PrintError(err.Error())
+				os.Exit(cfg.StatusConfigError)
+			}
+			err = bkpfile.CreateBackupWithTags(cfg, filePath, note, tagMap, dryRun)
 			if err != nil {
 				// Check if this is a success status
 				if backupErr, ok := err.(*bkpfile.BackupError); ok {
@@ -103,11 +139,84 @@ var (
 	}
 )
 
+// printWarningDiagnostics prints every warning-severity diagnostic through
+// the formatter before the caller proceeds with the loaded configuration.
+func printWarningDiagnostics(formatter *bkpfile.OutputFormatter, diags diag.Diagnostics) {
+	for _, d := range diags.Warnings() {
+		if d.Path != "" {
+			formatter.PrintError(fmt.Sprintf("config warning (%s): %s", d.Path, d.Summary))
+		} else {
+			formatter.PrintError(fmt.Sprintf("config warning: %s", d.Summary))
+		}
+	}
+}
+
+// parseTags turns repeated "--tag k=v" flag values into a map, rejecting
+// any entry that isn't in k=v form.
+func parseTags(raw []string) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(raw))
+	for _, kv := range raw {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid --tag %q, expected k=v", kv)
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// isGlobPattern reports whether path looks like a shell-style glob or
+// recursive ("**") pattern rather than a literal file path.
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[") || strings.Contains(path, "**")
+}
+
+// runGlobBackup expands pattern, filters it through --include/--exclude,
+// and backs up every surviving file via bkpfile.CreateBackupGlob,
+// printing one line per file and exiting with cfg.StatusConfigError if
+// any file in the batch failed.
+func runGlobBackup(formatter *bkpfile.OutputFormatter, cfg *bkpfile.Config, pattern, note string) error {
+	results, err := bkpfile.CreateBackupGlob(cfg, pattern, includePatterns, excludePatterns, note, dryRun)
+	if err != nil {
+		formatter.PrintError(fmt.Sprintf("failed to expand pattern %q: %v", pattern, err))
+		os.Exit(cfg.StatusConfigError)
+	}
+	if len(results) == 0 {
+		formatter.PrintError(fmt.Sprintf("no files matched %q", pattern))
+		os.Exit(cfg.StatusFileNotFound)
+	}
+
+	failed := 0
+	for _, result := range results {
+		relPath, relErr := filepath.Rel(".", result.Path)
+		if relErr != nil {
+			relPath = result.Path
+		}
+		if result.Err != nil {
+			failed++
+			formatter.PrintError(fmt.Sprintf("%s: %v", relPath, result.Err))
+			continue
+		}
+		fmt.Printf("Backed up: %s\n", relPath)
+	}
+	if failed > 0 {
+		os.Exit(cfg.StatusConfigError)
+	}
+	return nil
+}
+
 func init() {
 	// Add global flags
 	rootCmd.PersistentFlags().BoolVar(&dryRun, "dry-run", false, "Show what would be done without creating backups")
 	rootCmd.PersistentFlags().BoolVar(&list, "list", false, "List all backups for the specified file")
 	rootCmd.PersistentFlags().BoolVar(&config, "config", false, "Display computed configuration values and exit")
+	rootCmd.PersistentFlags().BoolVar(&prune, "prune", false, "Remove old backups for the specified file according to the retention policy")
+	rootCmd.PersistentFlags().StringArrayVar(&tags, "tag", nil, "Attach a k=v tag to the backup's metadata (may be repeated)")
+	rootCmd.PersistentFlags().StringArrayVar(&includePatterns, "include", nil, "Only back up files matching this gitignore-style pattern (may be repeated; implies glob expansion)")
+	rootCmd.PersistentFlags().StringArrayVar(&excludePatterns, "exclude", nil, "Skip files matching this gitignore-style pattern (may be repeated; a leading ! re-includes a subset)")
 
 	// Customize help template to include version
 	rootCmd.SetHelpTemplate(`{{with (or .Long .Short)}}{{. | trimTrailingWhitespaces}}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bkpfile/internal/bkpfile"
+
+	"github.com/spf13/cobra"
+)
+
+var pruneDryRun bool
+
+var pruneCmd = &cobra.Command{
+	Use:   "prune <file-path>",
+	Short: "Remove backups for a file that fall outside the retention policy",
+	Long: `Applies the configured retention policy (keep_last, keep_hourly,
+keep_daily, keep_weekly, keep_monthly, keep_within, max_total_bytes,
+min_free_bytes) to the backups of the given file and removes anything it
+doesn't keep. Setting retention.prune_after_backup in config makes the
+timestamp-driven backup path (CreateBackupWithTime, which the daemon uses)
+run this after each backup it creates; the root command's backup path does
+not prune.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, diags, err := bkpfile.LoadConfig(".")
+		if err != nil {
+			return err
+		}
+		printWarningDiagnostics(bkpfile.NewOutputFormatter(cfg), diags)
+
+		formatter := bkpfile.NewOutputFormatter(cfg)
+		_, err = bkpfile.PruneBackups(cfg, args[0], pruneDryRun)
+		if backupErr, ok := err.(*bkpfile.BackupError); ok {
+			if backupErr.StatusCode != cfg.StatusPruned {
+				formatter.PrintError(backupErr.Message)
+				return nil
+			}
+			cmd.Println(backupErr.Message)
+			return nil
+		}
+		return err
+	},
+}
+
+func init() {
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "Show what would be pruned without removing anything")
+	rootCmd.AddCommand(pruneCmd)
+}
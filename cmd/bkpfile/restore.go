@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+
+	"bkpfile/internal/bkpfile"
+
+	"github.com/spf13/cobra"
+)
+
+var restoreOwnership bool
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <backup> <dest>",
+	Short: "Restore a backup to a destination path",
+	Long: `Restores a backup created by bkpfile. Archive-format backups (tar,
+tar.gz, zip) are extracted into <dest> as a directory tree; plain
+single-file backups are copied to <dest> as a regular file.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, diags, err := bkpfile.LoadConfig(".")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		printWarningDiagnostics(bkpfile.NewOutputFormatter(cfg), diags)
+
+		opts := bkpfile.RestoreOptions{RestoreOwnership: restoreOwnership}
+		if err := bkpfile.RestoreBackup(cfg, args[0], args[1], opts); err != nil {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+
+		fmt.Printf("Restored %s to %s\n", args[0], args[1])
+		return nil
+	},
+}
+
+func init() {
+	restoreCmd.Flags().BoolVar(&restoreOwnership, "restore-ownership", false, "Restore the original uid/gid recorded in the backup's metadata")
+	rootCmd.AddCommand(restoreCmd)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+
+	"bkpfile/internal/bkpfile"
+
+	"github.com/spf13/cobra"
+)
+
+var serveAddr string
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve backup create/list/restore/delete operations over HTTP",
+	Long: `Starts an HTTP server exposing bkpfile's backup operations so another
+machine or a CI job can drive them remotely:
+
+  POST   /backups              multipart "file" (+ optional "note") -> CreateBackup
+  GET    /backups?source=...   -> ListBackups for that source file
+  GET    /backups/{name}       streams the named backup for restore
+  DELETE /backups/{name}       removes the named backup and its sidecar metadata
+
+Every request must carry "Authorization: Bearer <token>" matching the
+BKPFILE_SERVER_TOKEN environment variable. This makes bkpfile usable as a
+lightweight backup daemon accessible from other machines.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, diags, err := bkpfile.LoadConfig(".")
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		printWarningDiagnostics(bkpfile.NewOutputFormatter(cfg), diags)
+
+		token := os.Getenv("BKPFILE_SERVER_TOKEN")
+		if token == "" {
+			return fmt.Errorf("BKPFILE_SERVER_TOKEN must be set to a bearer token before serving")
+		}
+
+		server := bkpfile.NewServer(cfg, token)
+		fmt.Printf("Serving backups on %s\n", serveAddr)
+		return http.ListenAndServe(serveAddr, server)
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8077", "Address to listen on")
+	rootCmd.AddCommand(serveCmd)
+}
@@ -0,0 +1,119 @@
+// Package archive streams directory trees into tar, tar.gz, or zip archives
+// and back, so bkpfile can back up a directory the same way it backs up a
+// single file.
+package archive
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Format identifies an archive encoding.
+// Architecture: Data Objects - Format
+type Format string
+
+const (
+	// FormatNone means no archive: directories are not supported.
+	FormatNone Format = "none"
+	// FormatTar is an uncompressed POSIX tar archive.
+	FormatTar Format = "tar"
+	// FormatTarGz is a gzip-compressed tar archive.
+	FormatTarGz Format = "tar.gz"
+	// FormatZip is a zip archive.
+	FormatZip Format = "zip"
+)
+
+// ParseFormat validates a config-supplied archive_format string, treating
+// an empty string as FormatNone.
+// Architecture: Core Functions - Archive Management - ParseFormat
+func ParseFormat(s string) (Format, error) {
+	switch Format(s) {
+	case "":
+		return FormatNone, nil
+	case FormatNone, FormatTar, FormatTarGz, FormatZip:
+		return Format(s), nil
+	default:
+		return "", fmt.Errorf("unknown archive_format %q (want one of none, tar, tar.gz, zip)", s)
+	}
+}
+
+// Member describes one entry read from or written to an archive.
+// Architecture: Data Objects - Member
+type Member struct {
+	// Name is the archive-relative path, always slash-separated
+	// Architecture: Member.Name
+	Name string
+
+	// IsDir reports whether this member is a directory
+	// Architecture: Member.IsDir
+	IsDir bool
+
+	// LinkTarget is set when this member is a symlink
+	// Architecture: Member.LinkTarget
+	LinkTarget string
+
+	// Size is the content length in bytes for regular files
+	// Architecture: Member.Size
+	Size int64
+
+	// Mode is the member's file mode and permission bits
+	// Architecture: Member.Mode
+	Mode os.FileMode
+}
+
+// Writer streams files, directories, and symlinks into an archive.
+// Architecture: Core Functions - Archive Management - Writer
+type Writer interface {
+	// WriteFile adds a regular file member, reading its content from r.
+	WriteFile(member Member, r io.Reader) error
+	// WriteDir adds a directory member with no content.
+	WriteDir(member Member) error
+	// WriteSymlink adds a symlink member pointing at member.LinkTarget.
+	WriteSymlink(member Member) error
+	// Close finalizes the archive and flushes it to the underlying writer.
+	Close() error
+}
+
+// Reader streams the members of an archive in order for listing or
+// extraction. After Next returns a regular-file Member, Read returns that
+// member's content until the following Next call.
+// Architecture: Core Functions - Archive Management - Reader
+type Reader interface {
+	// Next advances to the next member, returning io.EOF when exhausted.
+	Next() (Member, error)
+	// Read reads from the current member's content.
+	Read(p []byte) (int, error)
+}
+
+// NewWriter returns a Writer that encodes format to w.
+// Architecture: Core Functions - Archive Management - NewWriter
+func NewWriter(format Format, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatTar:
+		return newTarWriter(w), nil
+	case FormatTarGz:
+		return newTarGzWriter(w), nil
+	case FormatZip:
+		return newZipWriter(w), nil
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %q", format)
+	}
+}
+
+// NewReader returns a Reader over the format-encoded archive in r. Zip
+// archives require random access, so callers must supply the archive's
+// total size alongside an io.ReaderAt (an *os.File satisfies both).
+// Architecture: Core Functions - Archive Management - NewReader
+func NewReader(format Format, r io.ReaderAt, size int64) (Reader, error) {
+	switch format {
+	case FormatTar:
+		return newTarReader(io.NewSectionReader(r, 0, size)), nil
+	case FormatTarGz:
+		return newTarGzReader(io.NewSectionReader(r, 0, size))
+	case FormatZip:
+		return newZipReader(r, size)
+	default:
+		return nil, fmt.Errorf("unsupported archive format: %q", format)
+	}
+}
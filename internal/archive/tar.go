@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+)
+
+// tarArchiveWriter implements Writer for both the plain tar and gzip-wrapped
+// tar.gz formats; gz is nil for plain tar.
+type tarArchiveWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func newTarWriter(w io.Writer) *tarArchiveWriter {
+	return &tarArchiveWriter{tw: tar.NewWriter(w)}
+}
+
+func newTarGzWriter(w io.Writer) *tarArchiveWriter {
+	gz := gzip.NewWriter(w)
+	return &tarArchiveWriter{gz: gz, tw: tar.NewWriter(gz)}
+}
+
+func (tw *tarArchiveWriter) WriteFile(member Member, r io.Reader) error {
+	hdr := &tar.Header{
+		Name:     member.Name,
+		Mode:     int64(member.Mode.Perm()),
+		Size:     member.Size,
+		Typeflag: tar.TypeReg,
+	}
+	if err := tw.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	_, err := io.Copy(tw.tw, r)
+	return err
+}
+
+func (tw *tarArchiveWriter) WriteDir(member Member) error {
+	hdr := &tar.Header{
+		Name:     member.Name + "/",
+		Mode:     int64(member.Mode.Perm()),
+		Typeflag: tar.TypeDir,
+	}
+	return tw.tw.WriteHeader(hdr)
+}
+
+func (tw *tarArchiveWriter) WriteSymlink(member Member) error {
+	hdr := &tar.Header{
+		Name:     member.Name,
+		Linkname: member.LinkTarget,
+		Typeflag: tar.TypeSymlink,
+	}
+	return tw.tw.WriteHeader(hdr)
+}
+
+func (tw *tarArchiveWriter) Close() error {
+	if err := tw.tw.Close(); err != nil {
+		return err
+	}
+	if tw.gz != nil {
+		return tw.gz.Close()
+	}
+	return nil
+}
+
+// tarArchiveReader implements Reader for both tar and tar.gz; gz is nil for
+// plain tar.
+type tarArchiveReader struct {
+	gz *gzip.Reader
+	tr *tar.Reader
+}
+
+func newTarReader(r io.Reader) *tarArchiveReader {
+	return &tarArchiveReader{tr: tar.NewReader(r)}
+}
+
+func newTarGzReader(r io.Reader) (*tarArchiveReader, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return &tarArchiveReader{gz: gz, tr: tar.NewReader(gz)}, nil
+}
+
+func (tr *tarArchiveReader) Next() (Member, error) {
+	hdr, err := tr.tr.Next()
+	if err != nil {
+		return Member{}, err
+	}
+
+	m := Member{
+		Name: strings.TrimSuffix(hdr.Name, "/"),
+		Size: hdr.Size,
+		Mode: os.FileMode(hdr.Mode),
+	}
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		m.IsDir = true
+	case tar.TypeSymlink:
+		m.LinkTarget = hdr.Linkname
+	}
+	return m, nil
+}
+
+func (tr *tarArchiveReader) Read(p []byte) (int, error) {
+	return tr.tr.Read(p)
+}
@@ -0,0 +1,111 @@
+package archive
+
+import (
+	"archive/zip"
+	"io"
+	"os"
+	"strings"
+)
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func newZipWriter(w io.Writer) *zipArchiveWriter {
+	return &zipArchiveWriter{zw: zip.NewWriter(w)}
+}
+
+func (w *zipArchiveWriter) WriteFile(member Member, r io.Reader) error {
+	hdr := &zip.FileHeader{Name: member.Name, Method: zip.Deflate}
+	hdr.SetMode(member.Mode)
+	fw, err := w.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(fw, r)
+	return err
+}
+
+func (w *zipArchiveWriter) WriteDir(member Member) error {
+	hdr := &zip.FileHeader{Name: member.Name + "/"}
+	hdr.SetMode(member.Mode | os.ModeDir)
+	_, err := w.zw.CreateHeader(hdr)
+	return err
+}
+
+func (w *zipArchiveWriter) WriteSymlink(member Member) error {
+	hdr := &zip.FileHeader{Name: member.Name}
+	hdr.SetMode(os.ModeSymlink | 0777)
+	fw, err := w.zw.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write([]byte(member.LinkTarget))
+	return err
+}
+
+func (w *zipArchiveWriter) Close() error {
+	return w.zw.Close()
+}
+
+type zipArchiveReader struct {
+	zr      *zip.Reader
+	idx     int
+	current io.ReadCloser
+}
+
+func newZipReader(r io.ReaderAt, size int64) (*zipArchiveReader, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return &zipArchiveReader{zr: zr}, nil
+}
+
+func (r *zipArchiveReader) Next() (Member, error) {
+	if r.current != nil {
+		r.current.Close()
+		r.current = nil
+	}
+	if r.idx >= len(r.zr.File) {
+		return Member{}, io.EOF
+	}
+	f := r.zr.File[r.idx]
+	r.idx++
+
+	m := Member{
+		Name: strings.TrimSuffix(f.Name, "/"),
+		Size: int64(f.UncompressedSize64),
+		Mode: f.Mode(),
+	}
+
+	if f.Mode()&os.ModeDir != 0 {
+		m.IsDir = true
+		return m, nil
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return Member{}, err
+	}
+
+	if f.Mode()&os.ModeSymlink != 0 {
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return Member{}, err
+		}
+		m.LinkTarget = string(data)
+		return m, nil
+	}
+
+	r.current = rc
+	return m, nil
+}
+
+func (r *zipArchiveReader) Read(p []byte) (int, error) {
+	if r.current == nil {
+		return 0, io.EOF
+	}
+	return r.current.Read(p)
+}
@@ -0,0 +1,421 @@
+package bkpfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"bkpfile/internal/archive"
+)
+
+// archiveFormatSuffix returns the filename suffix appended to a directory
+// backup's name for the given format, matching the extension convention of
+// the format itself (e.g. so the backup can be told apart from a plain file
+// backup, and so restore can infer the format back from the name).
+func archiveFormatSuffix(format archive.Format) string {
+	switch format {
+	case archive.FormatTar:
+		return ".tar"
+	case archive.FormatTarGz:
+		return ".tar.gz"
+	case archive.FormatZip:
+		return ".zip"
+	default:
+		return ""
+	}
+}
+
+// archiveFormatFromName infers the archive format of a backup from its
+// filename, ignoring a trailing age/gpg encryption suffix. It returns "" for
+// backups that are not archives (plain single-file backups).
+func archiveFormatFromName(name string) archive.Format {
+	plain := trimEncryptedSuffix(name)
+	switch {
+	case strings.HasSuffix(plain, ".tar.gz"):
+		return archive.FormatTarGz
+	case strings.HasSuffix(plain, ".tar"):
+		return archive.FormatTar
+	case strings.HasSuffix(plain, ".zip"):
+		return archive.FormatZip
+	default:
+		return ""
+	}
+}
+
+// archiveMemberDigest renders one archive member as a line in the canonical
+// representation hashDirectory and hashArchive hash, so that equal trees
+// produce equal hashes regardless of member ordering or archive encoding.
+func archiveMemberDigest(name string, isDir bool, linkTarget string, contentHash string) string {
+	kind := "f"
+	switch {
+	case isDir:
+		kind = "d"
+	case linkTarget != "":
+		kind = "l"
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s", kind, name, linkTarget, contentHash)
+}
+
+// hashDigestList sorts member digests, so ordering differences between two
+// otherwise-identical trees don't produce different hashes, then hashes the
+// joined result.
+func hashDigestList(digests []string) string {
+	sort.Strings(digests)
+	h := sha256.New()
+	for _, d := range digests {
+		io.WriteString(h, d)
+		io.WriteString(h, "\n")
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashFileLegacy returns the hex-encoded sha256 digest of a regular file's
+// content on the real filesystem. It predates the afero-based, pluggable-
+// algorithm hashFile in hash.go and is kept here unchanged because
+// hashDirectory's identity hash must stay sha256 regardless of
+// Config.HashAlgorithm for existing archive backups to keep matching.
+func hashFileLegacy(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashDirectory walks root and returns a deterministic identity hash built
+// from the sorted list of member paths and per-member content hashes, so two
+// directory trees with identical content hash identically even though the
+// eventual archive bytes (gzip timestamps, zip member order) would not.
+// Architecture: Core Functions - Backup Management - hashDirectory
+func hashDirectory(root string) (string, error) {
+	var digests []string
+
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+		relPath, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			digests = append(digests, archiveMemberDigest(relPath, false, target, ""))
+			return nil
+		}
+		if info.IsDir() {
+			digests = append(digests, archiveMemberDigest(relPath, true, "", ""))
+			return nil
+		}
+
+		contentHash, err := hashFileLegacy(path)
+		if err != nil {
+			return err
+		}
+		digests = append(digests, archiveMemberDigest(relPath, false, "", contentHash))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return hashDigestList(digests), nil
+}
+
+// hashArchive reads the members of the archive at path and returns the same
+// kind of identity hash hashDirectory produces, so a directory backup can be
+// compared against the directory it was taken from without relying on the
+// archive's (non-deterministic) raw bytes.
+// Architecture: Core Functions - Backup Management - hashArchive
+func hashArchive(path string, format archive.Format) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	r, err := archive.NewReader(format, f, info.Size())
+	if err != nil {
+		return "", err
+	}
+
+	var digests []string
+	for {
+		member, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		name := filepath.ToSlash(member.Name)
+		switch {
+		case member.IsDir:
+			digests = append(digests, archiveMemberDigest(name, true, "", ""))
+		case member.LinkTarget != "":
+			digests = append(digests, archiveMemberDigest(name, false, member.LinkTarget, ""))
+		default:
+			h := sha256.New()
+			if _, err := io.Copy(h, r); err != nil {
+				return "", err
+			}
+			digests = append(digests, archiveMemberDigest(name, false, "", hex.EncodeToString(h.Sum(nil))))
+		}
+	}
+
+	return hashDigestList(digests), nil
+}
+
+// isIdenticalDirBackup reports whether dirPath's contents match backup, an
+// archive-format backup, by comparing identity hashes rather than archive
+// bytes. Encrypted archive backups are treated as opaque, the same as
+// encrypted single-file backups with no usable identity.
+func isIdenticalDirBackup(dirPath string, backup Backup) (bool, error) {
+	if backup.Encrypted {
+		return false, nil
+	}
+
+	format := archiveFormatFromName(backup.Name)
+	if format == "" {
+		return false, nil
+	}
+
+	dirHash, err := hashDirectory(dirPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash source directory: %w", err)
+	}
+	archiveHash, err := hashArchive(backup.Path, format)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash existing backup: %w", err)
+	}
+
+	return dirHash == archiveHash, nil
+}
+
+// createArchiveBackup writes dirPath's contents to backupPath in the given
+// archive format, including empty directories and symlinks.
+// Architecture: Core Functions - Backup Management - createArchiveBackup
+func createArchiveBackup(dirPath, backupPath string, format archive.Format) error {
+	out, err := os.Create(backupPath)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w, err := archive.NewWriter(format, out)
+	if err != nil {
+		return err
+	}
+
+	walkErr := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == dirPath {
+			return nil
+		}
+		relPath, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return err
+		}
+		member := archive.Member{Name: filepath.ToSlash(relPath), Mode: info.Mode()}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			member.LinkTarget = target
+			return w.WriteSymlink(member)
+		}
+		if info.IsDir() {
+			member.IsDir = true
+			return w.WriteDir(member)
+		}
+
+		member.Size = info.Size()
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return w.WriteFile(member, f)
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	return w.Close()
+}
+
+// createEncryptedArchiveBackup writes dirPath's contents as an archive to a
+// temporary file alongside backupPath, then encrypts that temporary file
+// into backupPath, since age encrypts an io.Reader rather than a filesystem
+// tree directly.
+func createEncryptedArchiveBackup(cfg *Config, dirPath, backupPath string, format archive.Format) error {
+	tmp, err := os.CreateTemp(filepath.Dir(backupPath), ".bkpfile-archive-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+
+	if err := createArchiveBackup(dirPath, tmpPath, format); err != nil {
+		return err
+	}
+
+	return CopyFileEncrypted(cfg, tmpPath, backupPath)
+}
+
+// extractArchiveBackup extracts every member of the archive at backupPath
+// into destDir, recreating directories, files (with their original mode),
+// and symlinks.
+// Architecture: Core Functions - Backup Management - extractArchiveBackup
+func extractArchiveBackup(backupPath, destDir string, format archive.Format) error {
+	f, err := os.Open(backupPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	r, err := archive.NewReader(format, f, info.Size())
+	if err != nil {
+		return err
+	}
+
+	for {
+		member, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		destPath := filepath.Join(destDir, filepath.FromSlash(member.Name))
+
+		switch {
+		case member.IsDir:
+			if err := os.MkdirAll(destPath, 0755); err != nil {
+				return err
+			}
+		case member.LinkTarget != "":
+			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return err
+			}
+			os.Remove(destPath)
+			if err := os.Symlink(member.LinkTarget, destPath); err != nil {
+				return err
+			}
+		default:
+			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+				return err
+			}
+			mode := member.Mode
+			if mode == 0 {
+				mode = 0644
+			}
+			out, err := os.OpenFile(destPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode.Perm())
+			if err != nil {
+				return err
+			}
+			_, copyErr := io.Copy(out, r)
+			closeErr := out.Close()
+			if copyErr != nil {
+				return copyErr
+			}
+			if closeErr != nil {
+				return closeErr
+			}
+		}
+	}
+
+	return nil
+}
+
+// RestoreOptions controls optional behavior of RestoreBackup.
+// Architecture: Data Objects - RestoreOptions
+type RestoreOptions struct {
+	// RestoreOwnership chown()s the restored file to the uid/gid recorded
+	// in the backup's sidecar BackupMeta, when one exists. Requires
+	// appropriate privileges; ignored for archive/directory backups.
+	RestoreOwnership bool
+}
+
+// RestoreBackup restores the backup at backupPath into dest. Archive-format
+// backups (tar, tar.gz, zip) are extracted into dest as a directory tree;
+// plain single-file backups are copied to dest as a regular file. Encrypted
+// backups are decrypted first using the identity derived from cfg.Encryption.
+// Architecture: Core Functions - Backup Management - RestoreBackup
+func RestoreBackup(cfg *Config, backupPath, dest string, opts RestoreOptions) error {
+	name := filepath.Base(backupPath)
+	format := archiveFormatFromName(name)
+
+	sourcePath := backupPath
+	if isEncryptedBackupName(name) {
+		tmp, err := os.CreateTemp("", ".bkpfile-restore-*")
+		if err != nil {
+			return err
+		}
+		tmpPath := tmp.Name()
+		tmp.Close()
+		defer os.Remove(tmpPath)
+
+		if err := decryptFile(cfg, backupPath, tmpPath); err != nil {
+			return err
+		}
+		sourcePath = tmpPath
+	}
+
+	if format != "" {
+		if err := os.MkdirAll(dest, 0755); err != nil {
+			return fmt.Errorf("failed to create restore destination: %w", err)
+		}
+		return extractArchiveBackup(sourcePath, dest, format)
+	}
+
+	if err := CopyFileFS(cfg.fs(), sourcePath, dest); err != nil {
+		return err
+	}
+
+	if opts.RestoreOwnership {
+		if meta, err := readBackupMeta(cfg.fs(), backupPath); err == nil && meta != nil {
+			if err := os.Chown(dest, int(meta.UID), int(meta.GID)); err != nil {
+				return fmt.Errorf("failed to restore ownership: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,131 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// buildSampleTree creates a directory tree containing a regular file, a
+// nested file, an empty directory, and a symlink, for exercising archive
+// round-trips.
+func buildSampleTree(t *testing.T, root string) {
+	t.Helper()
+
+	if err := os.WriteFile(filepath.Join(root, "top.txt"), []byte("top level content"), 0644); err != nil {
+		t.Fatalf("Failed to write top.txt: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "nested"), 0755); err != nil {
+		t.Fatalf("Failed to create nested dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "nested", "inner.txt"), []byte("nested content"), 0644); err != nil {
+		t.Fatalf("Failed to write nested/inner.txt: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "empty"), 0755); err != nil {
+		t.Fatalf("Failed to create empty dir: %v", err)
+	}
+	if err := os.Symlink("top.txt", filepath.Join(root, "link-to-top")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+}
+
+func TestCreateBackupArchiveRoundTrip(t *testing.T) {
+	formats := []string{"tar", "tar.gz", "zip"}
+
+	for _, format := range formats {
+		t.Run(format, func(t *testing.T) {
+			tmpDir, err := os.MkdirTemp("", "bkpfile-archive-test-*")
+			if err != nil {
+				t.Fatalf("Failed to create temp dir: %v", err)
+			}
+			defer os.RemoveAll(tmpDir)
+
+			srcDir := filepath.Join(tmpDir, "src")
+			if err := os.MkdirAll(srcDir, 0755); err != nil {
+				t.Fatalf("Failed to create source dir: %v", err)
+			}
+			buildSampleTree(t, srcDir)
+
+			cfg := DefaultConfig()
+			cfg.BackupDirPath = filepath.Join(tmpDir, "backups")
+			cfg.ArchiveFormat = format
+
+			if err := CreateBackup(cfg, srcDir, "", false); err != nil {
+				if backupErr, ok := err.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusCreatedBackup {
+					t.Fatalf("CreateBackup() error = %v", err)
+				}
+			}
+
+			backups, err := ListBackups(cfg.BackupDirPath, srcDir)
+			if err != nil {
+				t.Fatalf("ListBackups() error = %v", err)
+			}
+			if len(backups) != 1 {
+				t.Fatalf("expected exactly one backup, got %d", len(backups))
+			}
+
+			destDir := filepath.Join(tmpDir, "restored")
+			if err := RestoreBackup(cfg, backups[0].Path, destDir, RestoreOptions{}); err != nil {
+				t.Fatalf("RestoreBackup() error = %v", err)
+			}
+
+			restoredContent, err := os.ReadFile(filepath.Join(destDir, "top.txt"))
+			if err != nil {
+				t.Fatalf("Failed to read restored top.txt: %v", err)
+			}
+			if string(restoredContent) != "top level content" {
+				t.Errorf("restored top.txt = %q, want %q", restoredContent, "top level content")
+			}
+
+			nestedContent, err := os.ReadFile(filepath.Join(destDir, "nested", "inner.txt"))
+			if err != nil {
+				t.Fatalf("Failed to read restored nested/inner.txt: %v", err)
+			}
+			if string(nestedContent) != "nested content" {
+				t.Errorf("restored nested/inner.txt = %q, want %q", nestedContent, "nested content")
+			}
+
+			if info, err := os.Stat(filepath.Join(destDir, "empty")); err != nil || !info.IsDir() {
+				t.Errorf("expected empty directory to be restored, stat error = %v", err)
+			}
+
+			linkTarget, err := os.Readlink(filepath.Join(destDir, "link-to-top"))
+			if err != nil {
+				t.Fatalf("Failed to read restored symlink: %v", err)
+			}
+			if linkTarget != "top.txt" {
+				t.Errorf("restored symlink target = %q, want %q", linkTarget, "top.txt")
+			}
+
+			// Backing up the identical directory again should be recognized
+			// as identical rather than producing a second archive.
+			err = CreateBackup(cfg, srcDir, "", false)
+			backupErr, ok := err.(*BackupError)
+			if !ok || backupErr.StatusCode != cfg.StatusFileIsIdenticalToExistingBackup {
+				t.Errorf("expected identical-backup status on second CreateBackup(), got %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateBackupDirectoryWithoutArchiveFormat(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-archive-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	srcDir := filepath.Join(tmpDir, "src")
+	if err := os.MkdirAll(srcDir, 0755); err != nil {
+		t.Fatalf("Failed to create source dir: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.BackupDirPath = filepath.Join(tmpDir, "backups")
+
+	err = CreateBackup(cfg, srcDir, "", false)
+	backupErr, ok := err.(*BackupError)
+	if !ok || backupErr.StatusCode != cfg.StatusInvalidFileType {
+		t.Errorf("expected StatusInvalidFileType error for a directory with archive_format=none, got %v", err)
+	}
+}
@@ -7,6 +7,10 @@ import (
 	"sort"
 	"strings"
 	"time"
+
+	"bkpfile/internal/archive"
+
+	"github.com/spf13/afero"
 )
 
 // timeNow is a variable that can be replaced for testing
@@ -52,6 +56,38 @@ type Backup struct {
 	// Note is an optional note for the backup
 	// Architecture: Backup.Note
 	Note string
+
+	// Encrypted reports whether this backup is an age-encrypted artifact
+	// Architecture: Backup.Encrypted
+	Encrypted bool
+
+	// ContentAddressed reports whether this backup is a chunk manifest
+	// written by CreateCDCBackup rather than a plain/archive/encrypted
+	// backup file. Path points at the manifest JSON, not at file content.
+	// Architecture: Backup.ContentAddressed
+	ContentAddressed bool
+
+	// Hash is the stored backup's content hash, populated from its sidecar
+	// BackupMeta if one was written alongside it. Empty if no sidecar
+	// exists (e.g. backups created before metadata.go landed).
+	// Architecture: Backup.Hash
+	Hash string
+
+	// HashAlgorithm is the algorithm Hash was computed with ("sha256",
+	// "xxhash", or "blake2b"), mirroring BackupMeta.HashAlgorithm.
+	// Architecture: Backup.HashAlgorithm
+	HashAlgorithm string
+
+	// SHA256 is a deprecated alias for Hash, kept for callers written
+	// before hash.go's pluggable HashAlgorithm landed. It is only
+	// populated when HashAlgorithm is "sha256".
+	// Architecture: Backup.SHA256
+	SHA256 string
+
+	// Tags holds the user-supplied "k=v" tags recorded in the backup's
+	// sidecar BackupMeta, if any.
+	// Architecture: Backup.Tags
+	Tags map[string]string
 }
 
 // GenerateBackupName generates a backup filename according to the specified format
@@ -72,48 +108,64 @@ func GenerateBackupName(sourcePath, timestamp, note string) string {
 	return name
 }
 
-// CopyFile creates an exact copy of the specified file
+// CopyFile creates an exact copy of the specified file on the real
+// filesystem. See CopyFileFS to copy on an arbitrary afero.Fs instead.
 // Architecture: Core Functions - File System Operations - CopyFile
 func CopyFile(src, dst string) error {
+	return CopyFileFS(afero.NewOsFs(), src, dst)
+}
+
+// CopyFileFS is CopyFile against an explicit afero.Fs instead of the real
+// filesystem.
+// Architecture: Core Functions - File System Operations - CopyFileFS
+func CopyFileFS(fsys afero.Fs, src, dst string) error {
 	// Read source file
-	data, err := os.ReadFile(src)
+	data, err := afero.ReadFile(fsys, src)
 	if err != nil {
 		return fmt.Errorf("failed to read source file: %w", err)
 	}
 
 	// Create destination directory if it doesn't exist
-	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+	if err := fsys.MkdirAll(filepath.Dir(dst), 0755); err != nil {
 		return fmt.Errorf("failed to create destination directory: %w", err)
 	}
 
 	// Write to destination file
-	if err := os.WriteFile(dst, data, 0644); err != nil {
+	if err := afero.WriteFile(fsys, dst, data, 0644); err != nil {
 		return fmt.Errorf("failed to write destination file: %w", err)
 	}
 
 	// Copy file permissions
-	srcInfo, err := os.Stat(src)
+	srcInfo, err := fsys.Stat(src)
 	if err != nil {
 		return fmt.Errorf("failed to get source file info: %w", err)
 	}
 
-	if err := os.Chmod(dst, srcInfo.Mode()); err != nil {
+	if err := fsys.Chmod(dst, srcInfo.Mode()); err != nil {
 		return fmt.Errorf("failed to copy file permissions: %w", err)
 	}
 
 	// Set modification time to match source file
-	if err := os.Chtimes(dst, time.Now(), srcInfo.ModTime()); err != nil {
+	if err := fsys.Chtimes(dst, time.Now(), srcInfo.ModTime()); err != nil {
 		return fmt.Errorf("failed to set file modification time: %w", err)
 	}
 
 	return nil
 }
 
-// ListBackups gets all backups for a specific file
+// ListBackups gets all backups for a specific file on the real filesystem.
+// See ListBackupsFS to list from an arbitrary afero.Fs instead.
 // Architecture: Core Functions - Backup Management - ListBackups
 func ListBackups(backupDir string, sourceFile string) ([]Backup, error) {
+	return ListBackupsFS(afero.NewOsFs(), backupDir, sourceFile)
+}
+
+// ListBackupsFS is ListBackups against an explicit afero.Fs instead of the
+// real filesystem.
+// Architecture: Core Functions - Backup Management - ListBackupsFS
+func ListBackupsFS(fsys afero.Fs, backupDir string, sourceFile string) ([]Backup, error) {
 	// Check if backup directory exists
-	if _, err := os.Stat(backupDir); os.IsNotExist(err) {
+	if _, err := fsys.Stat(backupDir); os.IsNotExist(err) {
 		return nil, nil // No backups exist yet, return empty list
 	}
 
@@ -139,47 +191,88 @@ func ListBackups(backupDir string, sourceFile string) ([]Backup, error) {
 	dir := filepath.Dir(sourcePath)
 	filename := filepath.Base(sourcePath)
 
-	// Construct the backup directory path
-	backupSubDir := filepath.Join(backupDir, dir)
-	if _, err := os.Stat(backupSubDir); os.IsNotExist(err) {
-		return nil, nil // No backups exist for this file
-	}
+	var backups []Backup
 
-	// List all files in backup directory
-	entries, err := os.ReadDir(backupSubDir)
+	// Plain/archive/encrypted backups live directly under backupDir,
+	// mirroring the source file's relative directory.
+	backupSubDir := filepath.Join(backupDir, dir)
+	entries, err := backupDirEntries(fsys, backupSubDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+		return nil, err
 	}
-
-	var backups []Backup
-	for _, entry := range entries {
-		if entry.IsDir() {
+	for _, info := range entries {
+		if info.IsDir() {
 			continue
 		}
 
-		// Check if the backup file matches the source filename
-		// The backup name format is: filename-timestamp[=note]
-		if !strings.HasPrefix(entry.Name(), filename+"-") {
+		// Sidecar metadata files are attached to their backup below, not
+		// listed as backups in their own right.
+		if strings.HasSuffix(info.Name(), metaSuffix) {
 			continue
 		}
 
-		// Get file info
-		info, err := entry.Info()
-		if err != nil {
+		// Check if the backup file matches the source filename
+		// The backup name format is: filename-timestamp[=note][.age|.gpg]
+		name := info.Name()
+		encrypted := isEncryptedBackupName(name)
+		plainName := trimEncryptedSuffix(name)
+		if !strings.HasPrefix(plainName, filename+"-") {
 			continue
 		}
 
-		// Create backup object
 		backup := Backup{
-			Name:         entry.Name(),
-			Path:         filepath.Join(backupSubDir, entry.Name()),
+			Name:         name,
+			Path:         filepath.Join(backupSubDir, name),
 			CreationTime: info.ModTime(),
 			SourceFile:   sourceFile,
+			Encrypted:    encrypted,
+		}
+		if idx := strings.LastIndex(plainName, "="); idx > 0 {
+			backup.Note = plainName[idx+1:]
+		}
+		if meta, err := readBackupMeta(fsys, backup.Path); err == nil && meta != nil {
+			backup.Hash = meta.Hash
+			backup.HashAlgorithm = meta.HashAlgorithm
+			if backup.HashAlgorithm == HashAlgorithmSHA256 {
+				backup.SHA256 = backup.Hash
+			}
+			backup.Tags = meta.Tags
+		}
+
+		backups = append(backups, backup)
+	}
+
+	// Content-addressed backups (CreateCDCBackup) record a JSON manifest
+	// under <backupDir>/snapshots/ instead, mirroring the same relative
+	// directory structure.
+	snapshotsSubDir := filepath.Join(backupDir, cdcSnapshotsDirName, dir)
+	entries, err = backupDirEntries(fsys, snapshotsSubDir)
+	if err != nil {
+		return nil, err
+	}
+	for _, info := range entries {
+		if info.IsDir() {
+			continue
+		}
+
+		name := info.Name()
+		plainName := strings.TrimSuffix(name, cdcManifestSuffix)
+		if plainName == name || !strings.HasPrefix(plainName, filename+"-") {
+			continue
 		}
 
-		// Extract note if present
-		if idx := strings.LastIndex(entry.Name(), "="); idx > 0 {
-			backup.Note = entry.Name()[idx+1:]
+		backup := Backup{
+			Name:             name,
+			Path:             filepath.Join(snapshotsSubDir, name),
+			CreationTime:     info.ModTime(),
+			SourceFile:       sourceFile,
+			ContentAddressed: true,
+		}
+		if idx := strings.LastIndex(plainName, "="); idx > 0 {
+			backup.Note = plainName[idx+1:]
+		}
+		if manifest, err := readChunkManifest(fsys, backup.Path); err == nil && manifest != nil {
+			backup.Tags = manifest.Tags
 		}
 
 		backups = append(backups, backup)
@@ -193,9 +286,29 @@ func ListBackups(backupDir string, sourceFile string) ([]Backup, error) {
 	return backups, nil
 }
 
-// CreateBackup creates a backup of the specified file
+// backupDirEntries lists the files directly inside dir, returning nil
+// (not an error) if dir does not exist.
+func backupDirEntries(fsys afero.Fs, dir string) ([]os.FileInfo, error) {
+	if _, err := fsys.Stat(dir); os.IsNotExist(err) {
+		return nil, nil
+	}
+	entries, err := afero.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %w", err)
+	}
+	return entries, nil
+}
+
+// CreateBackup creates a backup of the specified file.
 // Architecture: Core Functions - Backup Management - CreateBackup
 func CreateBackup(cfg *Config, filePath string, note string, dryRun bool) error {
+	return CreateBackupWithTags(cfg, filePath, note, nil, dryRun)
+}
+
+// CreateBackupWithTags is CreateBackup, additionally recording the given
+// "k=v" tags in the backup's sidecar BackupMeta (see metadata.go).
+// Architecture: Core Functions - Backup Management - CreateBackupWithTags
+func CreateBackupWithTags(cfg *Config, filePath string, note string, tags map[string]string, dryRun bool) error {
 	// Check if source file exists and is a regular file
 	fileInfo, err := os.Stat(filePath)
 	if err != nil {
@@ -207,10 +320,23 @@ func CreateBackup(cfg *Config, filePath string, note string, dryRun bool) error
 		}
 		return NewBackupError(fmt.Sprintf("failed to get file info: %v", err), cfg.StatusConfigError)
 	}
-	if !fileInfo.Mode().IsRegular() {
+	isDir := fileInfo.IsDir()
+	if !isDir && !fileInfo.Mode().IsRegular() {
 		return NewBackupError(fmt.Sprintf("not a regular file: %s", filePath), cfg.StatusInvalidFileType)
 	}
 
+	var archiveFormat archive.Format
+	if isDir {
+		format, err := archive.ParseFormat(cfg.ArchiveFormat)
+		if err != nil {
+			return NewBackupError(err.Error(), cfg.StatusConfigError)
+		}
+		if format == archive.FormatNone {
+			return NewBackupError(fmt.Sprintf("%s is a directory; set archive_format to tar, tar.gz, or zip to back up directories", filePath), cfg.StatusInvalidFileType)
+		}
+		archiveFormat = format
+	}
+
 	// Get the source path relative to current directory
 	sourcePath := filePath
 	if !filepath.IsAbs(filePath) {
@@ -241,7 +367,7 @@ func CreateBackup(cfg *Config, filePath string, note string, dryRun bool) error
 	// If there are existing backups, compare with the most recent one
 	if len(backups) > 0 {
 		mostRecent := backups[0] // ListBackups sorts by most recent first
-		identical, err := CompareFiles(filePath, mostRecent.Path)
+		identical, err := isIdenticalToBackup(cfg, filePath, mostRecent)
 		if err != nil {
 			return NewBackupError(fmt.Sprintf("failed to compare files: %v", err), cfg.StatusConfigError)
 		}
@@ -257,10 +383,36 @@ func CreateBackup(cfg *Config, filePath string, note string, dryRun bool) error
 		}
 	}
 
+	// A chunked source file goes through CreateCDCBackupWithTags instead
+	// of the plain-copy path below: it splits the file into content-defined
+	// chunks and stores only the ones not already present.
+	if !isDir && cfg.StorageMode == StorageModeChunked {
+		if dryRun {
+			fmt.Printf("Would create backup: %s\n", filepath.Join(cfg.BackupDirPath, cdcSnapshotsDirName, dir))
+			return NewBackupError("dry run completed", cfg.StatusCreatedBackup)
+		}
+		_, manifestPath, err := CreateCDCBackupWithTags(cfg, filePath, note, tags, timeNow)
+		if err != nil {
+			return NewBackupError(fmt.Sprintf("failed to create backup: %v", err), cfg.StatusConfigError)
+		}
+		relPath, err := filepath.Rel(".", manifestPath)
+		if err != nil {
+			relPath = manifestPath
+		}
+		fmt.Printf("Created backup: %s\n", relPath)
+		return NewBackupError("backup created successfully", cfg.StatusCreatedBackup)
+	}
+
 	// Generate backup name with just the filename and note
 	filename := filepath.Base(sourcePath)
 	timestamp := timeNow().Format("2006-01-02-15-04")
 	backupName := GenerateBackupName(filename, timestamp, note)
+	if isDir {
+		backupName += archiveFormatSuffix(archiveFormat)
+	}
+	if suffix := cfg.Encryption.suffix(); suffix != "" {
+		backupName += suffix
+	}
 
 	// Determine backup path
 	backupDir := cfg.BackupDirPath
@@ -290,8 +442,20 @@ func CreateBackup(cfg *Config, filePath string, note string, dryRun bool) error
 		return NewBackupError(fmt.Sprintf("failed to create backup directory: %v", err), cfg.StatusFailedToCreateBackupDirectory)
 	}
 
-	// Copy the file
-	if err := CopyFile(filePath, backupPath); err != nil {
+	// Copy the file (or archive the directory), encrypting it first if configured to do so
+	var copyErr error
+	switch {
+	case isDir && cfg.Encryption.mode() != "none":
+		copyErr = createEncryptedArchiveBackup(cfg, filePath, backupPath, archiveFormat)
+	case isDir:
+		copyErr = createArchiveBackup(filePath, backupPath, archiveFormat)
+	case cfg.Encryption.mode() != "none":
+		copyErr = CopyFileEncrypted(cfg, filePath, backupPath)
+	default:
+		copyErr = CopyFile(filePath, backupPath)
+	}
+	if copyErr != nil {
+		err := copyErr
 		if os.IsPermission(err) {
 			return NewBackupError(fmt.Sprintf("permission denied copying file: %v", err), cfg.StatusPermissionDenied)
 		}
@@ -299,9 +463,18 @@ func CreateBackup(cfg *Config, filePath string, note string, dryRun bool) error
 		if strings.Contains(err.Error(), "no space left") || strings.Contains(err.Error(), "disk full") {
 			return NewBackupError(fmt.Sprintf("disk full: %v", err), cfg.StatusDiskFull)
 		}
+		if cfg.Encryption.mode() != "none" {
+			return NewBackupError(fmt.Sprintf("failed to encrypt backup: %v", err), cfg.StatusEncryptionError)
+		}
 		return NewBackupError(fmt.Sprintf("failed to create backup: %v", err), cfg.StatusConfigError)
 	}
 
+	if !isDir {
+		if err := writeBackupMeta(cfg.fs(), filePath, backupPath, note, tags, cfg.HashAlgorithm); err != nil {
+			return NewBackupError(fmt.Sprintf("failed to write backup metadata: %v", err), cfg.StatusConfigError)
+		}
+	}
+
 	// Get relative path for display
 	relPath, err := filepath.Rel(".", backupPath)
 	if err != nil {
@@ -315,8 +488,10 @@ func CreateBackup(cfg *Config, filePath string, note string, dryRun bool) error
 // CreateBackupWithTime creates a backup of the specified file with a custom time function
 // This is used for testing to provide consistent timestamps
 func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool, now func() time.Time) error {
+	fsys := cfg.fs()
+
 	// Check if source file exists and is a regular file
-	fileInfo, err := os.Stat(filePath)
+	fileInfo, err := fsys.Stat(filePath)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return NewBackupError(fmt.Sprintf("file not found: %s", filePath), cfg.StatusFileNotFound)
@@ -326,10 +501,23 @@ func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool
 		}
 		return NewBackupError(fmt.Sprintf("failed to get file info: %v", err), cfg.StatusConfigError)
 	}
-	if !fileInfo.Mode().IsRegular() {
+	isDir := fileInfo.IsDir()
+	if !isDir && !fileInfo.Mode().IsRegular() {
 		return NewBackupError(fmt.Sprintf("not a regular file: %s", filePath), cfg.StatusInvalidFileType)
 	}
 
+	var archiveFormat archive.Format
+	if isDir {
+		format, err := archive.ParseFormat(cfg.ArchiveFormat)
+		if err != nil {
+			return NewBackupError(err.Error(), cfg.StatusConfigError)
+		}
+		if format == archive.FormatNone {
+			return NewBackupError(fmt.Sprintf("%s is a directory; set archive_format to tar, tar.gz, or zip to back up directories", filePath), cfg.StatusInvalidFileType)
+		}
+		archiveFormat = format
+	}
+
 	// Get the source path relative to current directory
 	sourcePath := filePath
 	if !filepath.IsAbs(filePath) {
@@ -352,7 +540,7 @@ func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool
 	dir := filepath.Dir(sourcePath)
 
 	// Check for existing backups
-	backups, err := ListBackups(cfg.BackupDirPath, filePath)
+	backups, err := ListBackupsFS(fsys, cfg.BackupDirPath, filePath)
 	if err != nil {
 		return NewBackupError(fmt.Sprintf("failed to list existing backups: %v", err), cfg.StatusConfigError)
 	}
@@ -360,7 +548,7 @@ func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool
 	// If there are existing backups, compare with the most recent one
 	if len(backups) > 0 {
 		mostRecent := backups[0] // ListBackups sorts by most recent first
-		identical, err := CompareFiles(filePath, mostRecent.Path)
+		identical, err := isIdenticalToBackup(cfg, filePath, mostRecent)
 		if err != nil {
 			return NewBackupError(fmt.Sprintf("failed to compare files: %v", err), cfg.StatusConfigError)
 		}
@@ -376,10 +564,36 @@ func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool
 		}
 	}
 
+	// A chunked source file goes through CreateCDCBackupWithTags instead
+	// of the plain-copy path below: it splits the file into content-defined
+	// chunks and stores only the ones not already present.
+	if !isDir && cfg.StorageMode == StorageModeChunked {
+		if dryRun {
+			fmt.Printf("Would create backup: %s\n", filepath.Join(cfg.BackupDirPath, cdcSnapshotsDirName, dir))
+			return NewBackupError("dry run completed", cfg.StatusCreatedBackup)
+		}
+		_, manifestPath, err := CreateCDCBackupWithTags(cfg, filePath, note, nil, now)
+		if err != nil {
+			return NewBackupError(fmt.Sprintf("failed to create backup: %v", err), cfg.StatusConfigError)
+		}
+		relPath, err := filepath.Rel(".", manifestPath)
+		if err != nil {
+			relPath = manifestPath
+		}
+		fmt.Printf("Created backup: %s\n", relPath)
+		return NewBackupError("backup created successfully", cfg.StatusCreatedBackup)
+	}
+
 	// Generate backup name with just the filename and note
 	filename := filepath.Base(sourcePath)
 	timestamp := now().Format("2006-01-02-15-04")
 	backupName := GenerateBackupName(filename, timestamp, note)
+	if isDir {
+		backupName += archiveFormatSuffix(archiveFormat)
+	}
+	if suffix := cfg.Encryption.suffix(); suffix != "" {
+		backupName += suffix
+	}
 
 	// Determine backup path
 	backupDir := cfg.BackupDirPath
@@ -398,7 +612,7 @@ func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool
 	}
 
 	// Create backup directory if it doesn't exist
-	if err := os.MkdirAll(backupSubDir, 0755); err != nil {
+	if err := fsys.MkdirAll(backupSubDir, 0755); err != nil {
 		if os.IsPermission(err) {
 			return NewBackupError(fmt.Sprintf("permission denied creating backup directory: %v", err), cfg.StatusPermissionDenied)
 		}
@@ -409,8 +623,20 @@ func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool
 		return NewBackupError(fmt.Sprintf("failed to create backup directory: %v", err), cfg.StatusFailedToCreateBackupDirectory)
 	}
 
-	// Copy the file
-	if err := CopyFile(filePath, backupPath); err != nil {
+	// Copy the file (or archive the directory), encrypting it first if configured to do so
+	var copyErr error
+	switch {
+	case isDir && cfg.Encryption.mode() != "none":
+		copyErr = createEncryptedArchiveBackup(cfg, filePath, backupPath, archiveFormat)
+	case isDir:
+		copyErr = createArchiveBackup(filePath, backupPath, archiveFormat)
+	case cfg.Encryption.mode() != "none":
+		copyErr = CopyFileEncrypted(cfg, filePath, backupPath)
+	default:
+		copyErr = putBackupViaStore(cfg, fsys, filePath, backupDir, backupPath)
+	}
+	if copyErr != nil {
+		err := copyErr
 		if os.IsPermission(err) {
 			return NewBackupError(fmt.Sprintf("permission denied copying file: %v", err), cfg.StatusPermissionDenied)
 		}
@@ -418,9 +644,18 @@ func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool
 		if strings.Contains(err.Error(), "no space left") || strings.Contains(err.Error(), "disk full") {
 			return NewBackupError(fmt.Sprintf("disk full: %v", err), cfg.StatusDiskFull)
 		}
+		if cfg.Encryption.mode() != "none" {
+			return NewBackupError(fmt.Sprintf("failed to encrypt backup: %v", err), cfg.StatusEncryptionError)
+		}
 		return NewBackupError(fmt.Sprintf("failed to create backup: %v", err), cfg.StatusConfigError)
 	}
 
+	if !isDir {
+		if err := writeBackupMeta(fsys, filePath, backupPath, note, nil, cfg.HashAlgorithm); err != nil {
+			return NewBackupError(fmt.Sprintf("failed to write backup metadata: %v", err), cfg.StatusConfigError)
+		}
+	}
+
 	// Get relative path for display
 	relPath, err := filepath.Rel(".", backupPath)
 	if err != nil {
@@ -428,19 +663,110 @@ func CreateBackupWithTime(cfg *Config, filePath string, note string, dryRun bool
 	}
 	fmt.Printf("Created backup: %s\n", relPath)
 
+	if cfg.Retention.PruneAfterBackup {
+		if _, pruneErr := PruneBackups(cfg, filePath, false); pruneErr != nil {
+			if backupErr, ok := pruneErr.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusPruned {
+				fmt.Printf("prune_after_backup: %v\n", pruneErr)
+			}
+		}
+	}
+
 	return NewBackupError("backup created successfully", cfg.StatusCreatedBackup)
 }
 
-// CompareFiles performs a byte-by-byte comparison of two files
+// isIdenticalToBackup reports whether filePath's contents match an existing
+// backup. Encrypted backups are decrypted and hashed when an identity is
+// available; otherwise they are treated as opaque (never identical), so a
+// new backup is created rather than silently skipped.
+// Architecture: Core Functions - Backup Management - isIdenticalToBackup
+func isIdenticalToBackup(cfg *Config, filePath string, backup Backup) (bool, error) {
+	sourceInfo, err := cfg.fs().Stat(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to stat source: %w", err)
+	}
+	if sourceInfo.IsDir() {
+		return isIdenticalDirBackup(filePath, backup)
+	}
+
+	if backup.ContentAddressed {
+		return isIdenticalToCDCBackup(cfg, filePath, backup.Path)
+	}
+
+	if !backup.Encrypted {
+		// A recorded hash lets us answer from the source file alone,
+		// without reading the backup's bytes back from the store - the
+		// fast path a remote BackupStore needs for identical-file
+		// detection to stay cheap.
+		if backup.Hash != "" {
+			if match, err := sourceMatchesHash(cfg.fs(), backup.HashAlgorithm, filePath, backup.Hash); err == nil {
+				return match, nil
+			}
+		}
+		return CompareFilesFS(cfg.fs(), filePath, backup.Path)
+	}
+
+	backupHash, ok, err := decryptAndHash(cfg, backup.Path)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+
+	sourceHash, err := hashFile(cfg.fs(), cfg.HashAlgorithm, filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash source file: %w", err)
+	}
+
+	return sourceHash == backupHash, nil
+}
+
+// putBackupViaStore writes filePath's contents to backupPath through
+// cfg.store() instead of directly through fsys, so a configured
+// RemoteBackupFS (e.g. a cachingBackupStore fronting a remote backend)
+// actually receives the write rather than being bypassed.
+// Architecture: Core Functions - Backup Management - putBackupViaStore
+func putBackupViaStore(cfg *Config, fsys afero.Fs, filePath, backupDir, backupPath string) error {
+	data, err := afero.ReadFile(fsys, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to read source file: %w", err)
+	}
+	key, err := filepath.Rel(backupDir, backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to compute backup store key: %w", err)
+	}
+	return cfg.store().Put(key, data)
+}
+
+// sourceMatchesHash reports whether filePath's contents hash to wantHash
+// under algo, without reading any existing backup bytes.
+func sourceMatchesHash(fsys afero.Fs, algo, filePath, wantHash string) (bool, error) {
+	got, err := hashFile(fsys, algo, filePath)
+	if err != nil {
+		return false, err
+	}
+	return got == wantHash, nil
+}
+
+// CompareFiles performs a byte-by-byte comparison of two files on the
+// real filesystem. See CompareFilesFS to compare files on an arbitrary
+// afero.Fs instead.
 // Architecture: Core Functions - File System Operations - CompareFiles
 func CompareFiles(file1, file2 string) (bool, error) {
+	return CompareFilesFS(afero.NewOsFs(), file1, file2)
+}
+
+// CompareFilesFS is CompareFiles against an explicit afero.Fs instead of
+// the real filesystem.
+// Architecture: Core Functions - File System Operations - CompareFilesFS
+func CompareFilesFS(fsys afero.Fs, file1, file2 string) (bool, error) {
 	// Read both files
-	data1, err := os.ReadFile(file1)
+	data1, err := afero.ReadFile(fsys, file1)
 	if err != nil {
 		return false, fmt.Errorf("failed to read first file: %w", err)
 	}
 
-	data2, err := os.ReadFile(file2)
+	data2, err := afero.ReadFile(fsys, file2)
 	if err != nil {
 		return false, fmt.Errorf("failed to read second file: %w", err)
 	}
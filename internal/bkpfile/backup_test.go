@@ -4,10 +4,13 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
 	"time"
+
+	"filippo.io/age"
 )
 
 func TestGenerateBackupName(t *testing.T) {
@@ -762,7 +765,7 @@ use_current_dir_name: true`
 	os.Unsetenv("BKPFILE_CONFIG")
 
 	// Load configuration (should find .bkpfile.yml file)
-	cfg, err := LoadConfig(".")
+	cfg, _, err := LoadConfig(".")
 	if err != nil {
 		t.Fatalf("LoadConfig() unexpected error: %v", err)
 	}
@@ -821,102 +824,78 @@ use_current_dir_name: true`
 }
 
 func TestConfigurationIntegration(t *testing.T) {
-	// Save original environment variable
-	originalEnv := os.Getenv("BKPFILE_CONFIG")
-	defer func() {
-		if originalEnv != "" {
-			os.Setenv("BKPFILE_CONFIG", originalEnv)
-		} else {
-			os.Unsetenv("BKPFILE_CONFIG")
-		}
-	}()
-
-	// Create temporary directory for testing
-	tmpDir, err := os.MkdirTemp("", "bkpfile-integration-test-*")
-	if err != nil {
-		t.Fatalf("Failed to create temp dir: %v", err)
-	}
-	defer os.RemoveAll(tmpDir)
-
-	// Change to temp directory for relative path testing
-	originalWd, err := os.Getwd()
-	if err != nil {
-		t.Fatalf("Failed to get current directory: %v", err)
-	}
-	if err := os.Chdir(tmpDir); err != nil {
-		t.Fatalf("Failed to change directory: %v", err)
-	}
-	defer os.Chdir(originalWd)
-
-	// Create test file
-	testFile := "test.txt"
-	if err := os.WriteFile(testFile, []byte("test content"), 0644); err != nil {
-		t.Fatalf("Failed to create test file: %v", err)
-	}
-
-	// Create custom configuration files
-	globalBackupDir := filepath.Join(tmpDir, "global-backup")
-	globalConfig := fmt.Sprintf(`backup_dir_path: "%s"
-use_current_dir_name: false`, globalBackupDir)
-
-	localConfig := `backup_dir_path: "./local-backup"
-use_current_dir_name: true`
-
-	if err := os.WriteFile("global.yml", []byte(globalConfig), 0644); err != nil {
-		t.Fatalf("Failed to create global config: %v", err)
-	}
-
-	if err := os.WriteFile("local.yml", []byte(localConfig), 0644); err != nil {
-		t.Fatalf("Failed to create local config: %v", err)
+	// Mock time.Now for consistent timestamps
+	mockTime := func() time.Time {
+		t, _ := time.Parse("2006-01-02-15-04", "2025-05-12-13-49")
+		return t
 	}
 
 	tests := []struct {
 		name           string
-		envValue       string
-		expectedBackup string
+		envValue       func(env *TestEnv) string
+		expectedBackup func(env *TestEnv) string
 		expectedUseDir bool
 	}{
 		{
-			name:           "backup with global config",
-			envValue:       "global.yml",
-			expectedBackup: globalBackupDir,
+			name: "backup with global config",
+			envValue: func(env *TestEnv) string {
+				return "global.yml"
+			},
+			expectedBackup: func(env *TestEnv) string {
+				return filepath.Join(env.Dir, "global-backup")
+			},
 			expectedUseDir: false,
 		},
 		{
-			name:           "backup with local config",
-			envValue:       "local.yml",
-			expectedBackup: "./local-backup",
+			name: "backup with local config",
+			envValue: func(env *TestEnv) string {
+				return "local.yml"
+			},
+			expectedBackup: func(env *TestEnv) string {
+				return "./local-backup"
+			},
 			expectedUseDir: true,
 		},
 		{
-			name:           "backup with config precedence",
-			envValue:       "global.yml:local.yml",
-			expectedBackup: globalBackupDir,
+			name: "backup with config precedence",
+			envValue: func(env *TestEnv) string {
+				return "global.yml:local.yml"
+			},
+			expectedBackup: func(env *TestEnv) string {
+				return filepath.Join(env.Dir, "global-backup")
+			},
 			expectedUseDir: false,
 		},
 	}
 
-	// Mock time.Now for consistent timestamps
-	mockTime := func() time.Time {
-		t, _ := time.Parse("2006-01-02-15-04", "2025-05-12-13-49")
-		return t
-	}
-
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			env, _ := newTestEnv(t)
+
+			testFile := "test.txt"
+			env.CreateFile(testFile, "test content")
+
+			globalBackupDir := filepath.Join(env.Dir, "global-backup")
+			env.WriteConfig("global.yml", fmt.Sprintf(`backup_dir_path: "%s"
+use_current_dir_name: false`, globalBackupDir))
+			env.WriteConfig("local.yml", `backup_dir_path: "./local-backup"
+use_current_dir_name: true`)
+
+			expectedBackup := tt.expectedBackup(env)
+
 			// Set environment variable
-			os.Setenv("BKPFILE_CONFIG", tt.envValue)
+			t.Setenv("BKPFILE_CONFIG", tt.envValue(env))
 
 			// Load configuration
-			cfg, err := LoadConfig(".")
+			cfg, _, err := env.LoadConfig()
 			if err != nil {
 				t.Errorf("LoadConfig() unexpected error: %v", err)
 				return
 			}
 
 			// Verify configuration values
-			if cfg.BackupDirPath != tt.expectedBackup {
-				t.Errorf("LoadConfig().BackupDirPath = %q, want %q", cfg.BackupDirPath, tt.expectedBackup)
+			if cfg.BackupDirPath != expectedBackup {
+				t.Errorf("LoadConfig().BackupDirPath = %q, want %q", cfg.BackupDirPath, expectedBackup)
 			}
 
 			if cfg.UseCurrentDirName != tt.expectedUseDir {
@@ -975,4 +954,215 @@ use_current_dir_name: true`
 			os.RemoveAll(cfg.BackupDirPath)
 		})
 	}
+
+	t.Run("backup with in-memory config", func(t *testing.T) {
+		t.Cleanup(func() { SetConfigPath("") })
+
+		env, _ := newTestEnv(t)
+		testFile := "test.txt"
+		env.CreateFile(testFile, "test content")
+
+		memoryBackupDir := filepath.Join(env.Dir, "memory-backup")
+		SetInMemoryConfig("integration-test", []byte(fmt.Sprintf(`backup_dir_path: "%s"
+use_current_dir_name: false`, memoryBackupDir)))
+		if err := SetConfigPath(InMemoryConfigPath + "integration-test"); err != nil {
+			t.Fatalf("SetConfigPath() error: %v", err)
+		}
+
+		cfg, _, err := env.LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig() unexpected error: %v", err)
+		}
+		if cfg.BackupDirPath != memoryBackupDir {
+			t.Errorf("LoadConfig().BackupDirPath = %q, want %q", cfg.BackupDirPath, memoryBackupDir)
+		}
+
+		if err := os.MkdirAll(cfg.BackupDirPath, 0755); err != nil {
+			t.Fatalf("Failed to create backup directory: %v", err)
+		}
+
+		if err := CreateBackupWithTime(cfg, testFile, "config_test", false, mockTime); err != nil {
+			if backupErr, ok := err.(*BackupError); !ok || backupErr.Message != "backup created successfully" {
+				t.Errorf("CreateBackupWithTime() error: %v", err)
+			}
+		}
+
+		backups, err := ListBackups(cfg.BackupDirPath, testFile)
+		if err != nil {
+			t.Errorf("ListBackups() error: %v", err)
+		}
+		if len(backups) == 0 {
+			t.Error("No backups found after creation with in-memory config")
+		}
+	})
+
+	t.Run("in-memory config takes precedence over BKPFILE_CONFIG", func(t *testing.T) {
+		t.Cleanup(func() { SetConfigPath("") })
+		t.Setenv("BKPFILE_CONFIG", "global.yml")
+
+		env, _ := newTestEnv(t)
+
+		memoryBackupDir := filepath.Join(env.Dir, "memory-precedence-backup")
+		SetInMemoryConfig("integration-test-precedence", []byte(fmt.Sprintf(`backup_dir_path: "%s"
+use_current_dir_name: false`, memoryBackupDir)))
+		if err := SetConfigPath(InMemoryConfigPath + "integration-test-precedence"); err != nil {
+			t.Fatalf("SetConfigPath() error: %v", err)
+		}
+
+		cfg, _, err := env.LoadConfig()
+		if err != nil {
+			t.Fatalf("LoadConfig() unexpected error: %v", err)
+		}
+		if cfg.BackupDirPath != memoryBackupDir {
+			t.Errorf("LoadConfig().BackupDirPath = %q, want %q (in-memory config should win over BKPFILE_CONFIG)", cfg.BackupDirPath, memoryBackupDir)
+		}
+	})
+}
+
+// TestEncryptionModeIntegration is parallel to TestConfigurationIntegration:
+// it loads a per-test config from disk (this time with an encryption:
+// block) and drives CreateBackupWithTime/ListBackups/isIdenticalToBackup
+// through it, rather than constructing an EncryptionConfig by hand.
+func TestEncryptionModeIntegration(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-encryption-integration-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	mockTime := func() time.Time {
+		t, _ := time.Parse("2006-01-02-15-04", "2025-05-12-13-49")
+		return t
+	}
+
+	t.Run("age mode with per-config recipient", func(t *testing.T) {
+		identity, err := age.GenerateX25519Identity()
+		if err != nil {
+			t.Fatalf("failed to generate age identity: %v", err)
+		}
+
+		backupDir := filepath.Join(tmpDir, "age-backup")
+		config := fmt.Sprintf(`backup_dir_path: %q
+encryption:
+  mode: age
+  recipients: ["%s"]
+`, backupDir, identity.Recipient().String())
+		if err := os.WriteFile(".bkpfile.yml", []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		os.Setenv("BKPFILE_CONFIG", ".bkpfile.yml")
+		defer os.Unsetenv("BKPFILE_CONFIG")
+
+		cfg, _, err := LoadConfig(".")
+		if err != nil {
+			t.Fatalf("LoadConfig() unexpected error: %v", err)
+		}
+		if cfg.Encryption.mode() != "age" {
+			t.Fatalf("cfg.Encryption.mode() = %q, want %q", cfg.Encryption.mode(), "age")
+		}
+
+		testFile := "age-test.txt"
+		if err := os.WriteFile(testFile, []byte("age mode content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		if err := CreateBackupWithTime(cfg, testFile, "first", false, mockTime); err != nil {
+			if backupErr, ok := err.(*BackupError); !ok || backupErr.Message != "backup created successfully" {
+				t.Fatalf("CreateBackupWithTime() error: %v", err)
+			}
+		}
+
+		backups, err := ListBackups(cfg.BackupDirPath, testFile)
+		if err != nil {
+			t.Fatalf("ListBackups() error: %v", err)
+		}
+		if len(backups) != 1 {
+			t.Fatalf("ListBackups() returned %d backups, want 1", len(backups))
+		}
+		if !backups[0].Encrypted {
+			t.Error("ListBackups() did not mark the .age backup as encrypted")
+		}
+		if !strings.HasSuffix(backups[0].Name, encryptedSuffix) {
+			t.Errorf("backup name %q missing %q suffix", backups[0].Name, encryptedSuffix)
+		}
+
+		// Without an identity_file, the backup is opaque: CreateBackupWithTime
+		// must not mistake it for "identical to existing backup" and should
+		// produce a second backup (under a different note so it doesn't
+		// collide with the first at the same mocked timestamp).
+		if err := CreateBackupWithTime(cfg, testFile, "second", false, mockTime); err != nil {
+			if backupErr, ok := err.(*BackupError); !ok || backupErr.Message != "backup created successfully" {
+				t.Fatalf("second CreateBackupWithTime() error: %v", err)
+			}
+		}
+		backups, err = ListBackups(cfg.BackupDirPath, testFile)
+		if err != nil {
+			t.Fatalf("ListBackups() error: %v", err)
+		}
+		if len(backups) != 2 {
+			t.Fatalf("ListBackups() returned %d backups without an identity, want 2 (opaque comparisons never match)", len(backups))
+		}
+
+		// Writing the identity out so decryptAndHash can use it makes
+		// "file is identical to existing backup" fire on the next attempt.
+		identityFile := filepath.Join(tmpDir, "age-identity.txt")
+		if err := os.WriteFile(identityFile, []byte(identity.String()), 0600); err != nil {
+			t.Fatalf("Failed to write identity file: %v", err)
+		}
+		cfg.Encryption.IdentityFile = identityFile
+
+		err = CreateBackupWithTime(cfg, testFile, "third", false, mockTime)
+		if backupErr, ok := err.(*BackupError); !ok || backupErr.Message != "file is identical to existing backup" {
+			t.Fatalf("CreateBackupWithTime() with identity_file = %v, want file-is-identical", err)
+		}
+	})
+
+	t.Run("gpg mode with per-config recipient", func(t *testing.T) {
+		if _, err := exec.LookPath("gpg"); err != nil {
+			t.Skip("gpg not installed; skipping gpg mode integration test")
+		}
+
+		backupDir := filepath.Join(tmpDir, "gpg-backup")
+		config := fmt.Sprintf(`backup_dir_path: %q
+encryption:
+  mode: gpg
+  recipients: ["test@example.com"]
+`, backupDir)
+		if err := os.WriteFile(".bkpfile.yml", []byte(config), 0644); err != nil {
+			t.Fatalf("Failed to write config: %v", err)
+		}
+		os.Setenv("BKPFILE_CONFIG", ".bkpfile.yml")
+		defer os.Unsetenv("BKPFILE_CONFIG")
+
+		cfg, _, err := LoadConfig(".")
+		if err != nil {
+			t.Fatalf("LoadConfig() unexpected error: %v", err)
+		}
+		if cfg.Encryption.mode() != "gpg" {
+			t.Fatalf("cfg.Encryption.mode() = %q, want %q", cfg.Encryption.mode(), "gpg")
+		}
+
+		testFile := "gpg-test.txt"
+		if err := os.WriteFile(testFile, []byte("gpg mode content"), 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+
+		// No usable public key for test@example.com is imported into the
+		// test environment's keyring, so encryption is expected to fail;
+		// this still exercises mode selection, the .gpg suffix, and the
+		// gpg code path without depending on a real keyring being present.
+		err = CreateBackupWithTime(cfg, testFile, "", false, mockTime)
+		if backupErr, ok := err.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusEncryptionError {
+			t.Fatalf("CreateBackupWithTime() = %v, want a StatusEncryptionError BackupError", err)
+		}
+	})
 }
@@ -0,0 +1,51 @@
+package bkpfile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestCreateBackupWithTimeMemMapFs(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+
+	const sourceFile = "/src/notes.txt"
+	if err := afero.WriteFile(fsys, sourceFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to seed source file: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.FS = fsys
+	cfg.BackupDirPath = "/backups"
+
+	mockTime := func() time.Time {
+		return time.Date(2025, 5, 12, 13, 49, 0, 0, time.UTC)
+	}
+
+	if err := CreateBackupWithTime(cfg, sourceFile, "", false, mockTime); err != nil {
+		if _, ok := err.(*BackupError); !ok {
+			t.Fatalf("CreateBackupWithTime() error = %v", err)
+		}
+	}
+
+	backups, err := ListBackupsFS(fsys, cfg.BackupDirPath, sourceFile)
+	if err != nil {
+		t.Fatalf("ListBackupsFS() error = %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("ListBackupsFS() returned %d backups, want 1", len(backups))
+	}
+
+	identical, err := CompareFilesFS(fsys, sourceFile, backups[0].Path)
+	if err != nil {
+		t.Fatalf("CompareFilesFS() error = %v", err)
+	}
+	if !identical {
+		t.Errorf("backup content does not match source file")
+	}
+
+	if exists, err := afero.Exists(fsys, sourceFile); err != nil || !exists {
+		t.Errorf("expected CreateBackupWithTime() to leave the source file in place")
+	}
+}
@@ -0,0 +1,79 @@
+package bkpfile
+
+import (
+	"io"
+	"math/rand"
+)
+
+// Content-defined chunking targets, modeled on restic/FastCDC defaults: a
+// chunk boundary is expected on average cdcAvgChunkSize bytes past
+// cdcMinChunkSize, but never before cdcMinChunkSize and always by
+// cdcMaxChunkSize.
+const (
+	cdcMinChunkSize = 512 * 1024
+	cdcAvgChunkSize = 1024 * 1024
+	cdcMaxChunkSize = 4 * 1024 * 1024
+)
+
+// cdcMask is tested against the rolling hash's low bits after
+// cdcMinChunkSize bytes; cdcAvgChunkSize is a power of two, so past that
+// minimum a boundary is declared on average once every cdcAvgChunkSize
+// bytes.
+const cdcMask = uint64(cdcAvgChunkSize - 1)
+
+// gearTable holds the fixed pseudo-random constants the rolling hash
+// mixes in one per input byte value (a "gear hash", as used by FastCDC).
+// It is seeded deterministically so chunk boundaries - and therefore
+// chunk hashes - are stable across processes and machines.
+var gearTable = buildGearTable()
+
+func buildGearTable() [256]uint64 {
+	var table [256]uint64
+	rnd := rand.New(rand.NewSource(0x626b7066696c65))
+	for i := range table {
+		table[i] = rnd.Uint64()
+	}
+	return table
+}
+
+// chunkReader splits r into content-defined chunks using a FastCDC-style
+// rolling hash over a gear table: identical runs of bytes produce
+// identical chunk boundaries regardless of where they appear in the
+// stream or in surrounding data, which is what lets CreateCDCBackup
+// deduplicate chunks across files and versions.
+// Architecture: Core Functions - Backup Management - chunkReader
+func chunkReader(r io.Reader) ([][]byte, error) {
+	buf := make([]byte, 32*1024)
+	current := make([]byte, 0, cdcMaxChunkSize)
+	var chunks [][]byte
+	var hash uint64
+
+	flush := func() {
+		chunks = append(chunks, current)
+		current = make([]byte, 0, cdcMaxChunkSize)
+		hash = 0
+	}
+
+	for {
+		n, err := r.Read(buf)
+		for i := 0; i < n; i++ {
+			b := buf[i]
+			current = append(current, b)
+			hash = (hash << 1) + gearTable[b]
+
+			atTarget := len(current) >= cdcMinChunkSize && hash&cdcMask == 0
+			if atTarget || len(current) >= cdcMaxChunkSize {
+				flush()
+			}
+		}
+		if err == io.EOF {
+			if len(current) > 0 {
+				flush()
+			}
+			return chunks, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
@@ -0,0 +1,372 @@
+package bkpfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+const (
+	// cdcDataDirName is the subdirectory of a Config.BackupDirPath that
+	// holds content-addressed chunks, sharded by the first two hex
+	// characters of each chunk's SHA-256 hash.
+	cdcDataDirName = "data"
+
+	// cdcSnapshotsDirName is the subdirectory of a Config.BackupDirPath
+	// that holds ChunkManifest JSON files, mirroring the relative
+	// directory layout plain backups use.
+	cdcSnapshotsDirName = "snapshots"
+
+	// cdcManifestSuffix is appended to a ChunkManifest's backup name,
+	// analogous to encryptedSuffix for age-encrypted backups.
+	cdcManifestSuffix = ".json"
+)
+
+// Recognized values for Config.StorageMode.
+const (
+	// StorageModeFull is the default: CreateBackup stores one full copy
+	// of the source file per backup.
+	StorageModeFull = ""
+	// StorageModeChunked makes CreateBackup delegate to CreateCDCBackup,
+	// splitting the source file into content-defined chunks so repeated
+	// backups only store the bytes that actually changed.
+	StorageModeChunked = "chunked"
+)
+
+// validateStorageMode reports an error if mode is not a recognized
+// Config.StorageMode value.
+// Architecture: Core Functions - Backup Management - validateStorageMode
+func validateStorageMode(mode string) error {
+	switch mode {
+	case StorageModeFull, StorageModeChunked:
+		return nil
+	default:
+		return fmt.Errorf("unsupported storage mode %q", mode)
+	}
+}
+
+// ChunkManifest is the JSON document CreateCDCBackup writes under
+// <BackupDirPath>/snapshots/ for one content-addressed backup: enough to
+// list it alongside plain backups and to reassemble it on restore without
+// touching the file content itself.
+// Architecture: Data Objects - ChunkManifest
+type ChunkManifest struct {
+	// SourceFile is the path to the file this backup was taken from.
+	SourceFile string `json:"source_file"`
+	// Timestamp is when the backup was created.
+	Timestamp time.Time `json:"timestamp"`
+	// Note is the optional note supplied for this backup.
+	Note string `json:"note,omitempty"`
+	// Mode is the source file's permission bits at backup time.
+	Mode uint32 `json:"mode"`
+	// ModTime is the source file's modification time at backup time.
+	ModTime time.Time `json:"mod_time"`
+	// Chunks is the ordered list of SHA-256 hex digests that reassemble
+	// into the source file's content, in order.
+	Chunks []string `json:"chunks"`
+	// Tags holds user-supplied "--tag k=v" labels for this backup, set by
+	// CreateCDCBackupWithTags.
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// chunkShard returns the two-character shard directory a chunk hash is
+// stored under, e.g. "ab" for a hash beginning "ab1234...".
+func chunkShard(hash string) string {
+	if len(hash) < 2 {
+		return hash
+	}
+	return hash[:2]
+}
+
+// chunkPath returns the path a chunk with the given hash is stored at
+// under backupDir.
+func chunkPath(backupDir, hash string) string {
+	return filepath.Join(backupDir, cdcDataDirName, chunkShard(hash), hash)
+}
+
+// CreateCDCBackup splits filePath into content-defined chunks (see
+// chunkReader) and writes any chunk not already present under
+// <cfg.BackupDirPath>/data/, then records the backup as a ChunkManifest
+// under <cfg.BackupDirPath>/snapshots/. Because a chunk's path is its
+// SHA-256 hash, and identical hashes always mean identical bytes, writing
+// a chunk that already exists is always skipped - this is what gives
+// cross-file and cross-version deduplication for large files that only
+// change partially.
+// Architecture: Core Functions - Backup Management - CreateCDCBackup
+func CreateCDCBackup(cfg *Config, filePath string, note string, now func() time.Time) (*ChunkManifest, error) {
+	manifest, _, err := CreateCDCBackupWithTags(cfg, filePath, note, nil, now)
+	return manifest, err
+}
+
+// CreateCDCBackupWithTags is CreateCDCBackup, additionally recording the
+// given "k=v" tags in the resulting ChunkManifest and returning the path
+// the manifest was written to.
+// Architecture: Core Functions - Backup Management - CreateCDCBackupWithTags
+func CreateCDCBackupWithTags(cfg *Config, filePath string, note string, tags map[string]string, now func() time.Time) (*ChunkManifest, string, error) {
+	fsys := cfg.fs()
+
+	info, err := fsys.Stat(filePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to stat source file: %w", err)
+	}
+	if info.IsDir() {
+		return nil, "", fmt.Errorf("%s is a directory; CreateCDCBackup only backs up regular files", filePath)
+	}
+
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer f.Close()
+
+	chunks, err := chunkReader(f)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to chunk source file: %w", err)
+	}
+
+	hashes := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		sum := sha256.Sum256(chunk)
+		hash := hex.EncodeToString(sum[:])
+		hashes[i] = hash
+
+		path := chunkPath(cfg.BackupDirPath, hash)
+		exists, err := afero.Exists(fsys, path)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to check chunk %s: %w", hash, err)
+		}
+		if exists {
+			continue
+		}
+
+		if err := fsys.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, "", fmt.Errorf("failed to create chunk directory: %w", err)
+		}
+		if err := afero.WriteFile(fsys, path, chunk, 0644); err != nil {
+			return nil, "", fmt.Errorf("failed to write chunk %s: %w", hash, err)
+		}
+	}
+
+	manifest := &ChunkManifest{
+		SourceFile: filePath,
+		Timestamp:  now(),
+		Note:       note,
+		Mode:       uint32(info.Mode().Perm()),
+		ModTime:    info.ModTime(),
+		Chunks:     hashes,
+		Tags:       tags,
+	}
+
+	sourcePath := filePath
+	if !filepath.IsAbs(filePath) {
+		absPath, err := filepath.Abs(filePath)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get absolute path: %w", err)
+		}
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get working directory: %w", err)
+		}
+		relPath, err := filepath.Rel(wd, absPath)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get relative path: %w", err)
+		}
+		sourcePath = relPath
+	}
+
+	dir := filepath.Dir(sourcePath)
+	snapshotsSubDir := filepath.Join(cfg.BackupDirPath, cdcSnapshotsDirName, dir)
+	if err := fsys.MkdirAll(snapshotsSubDir, 0755); err != nil {
+		return nil, "", fmt.Errorf("failed to create snapshots directory: %w", err)
+	}
+
+	name := GenerateBackupName(filepath.Base(filePath), manifest.Timestamp.Format("2006-01-02-15-04"), note) + cdcManifestSuffix
+	manifestPath := filepath.Join(snapshotsSubDir, name)
+
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := afero.WriteFile(fsys, manifestPath, data, 0644); err != nil {
+		return nil, "", fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	return manifest, manifestPath, nil
+}
+
+// readChunkManifest reads and parses the ChunkManifest at manifestPath.
+func readChunkManifest(fsys afero.Fs, manifestPath string) (*ChunkManifest, error) {
+	data, err := afero.ReadFile(fsys, manifestPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest ChunkManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", manifestPath, err)
+	}
+	return &manifest, nil
+}
+
+// isIdenticalToCDCBackup reports whether filePath's content-defined chunks
+// exactly match the ordered chunk list recorded in the ChunkManifest at
+// manifestPath, without writing anything or re-reading any stored chunk.
+func isIdenticalToCDCBackup(cfg *Config, filePath, manifestPath string) (bool, error) {
+	fsys := cfg.fs()
+
+	manifest, err := readChunkManifest(fsys, manifestPath)
+	if err != nil {
+		return false, err
+	}
+
+	f, err := fsys.Open(filePath)
+	if err != nil {
+		return false, fmt.Errorf("failed to open source file: %w", err)
+	}
+	defer f.Close()
+
+	chunks, err := chunkReader(f)
+	if err != nil {
+		return false, fmt.Errorf("failed to chunk source file: %w", err)
+	}
+	if len(chunks) != len(manifest.Chunks) {
+		return false, nil
+	}
+	for i, chunk := range chunks {
+		sum := sha256.Sum256(chunk)
+		if hex.EncodeToString(sum[:]) != manifest.Chunks[i] {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// RestoreCDCBackup reassembles the backup recorded by the manifest at
+// manifestPath and writes it to dest, restoring the source file's
+// original permission bits and modification time.
+// Architecture: Core Functions - Backup Management - RestoreCDCBackup
+func RestoreCDCBackup(cfg *Config, manifestPath, dest string) error {
+	fsys := cfg.fs()
+
+	data, err := afero.ReadFile(fsys, manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest: %w", err)
+	}
+	var manifest ChunkManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return fmt.Errorf("failed to parse manifest %s: %w", manifestPath, err)
+	}
+
+	if err := fsys.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("failed to create restore destination: %w", err)
+	}
+
+	out, err := fsys.OpenFile(dest, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open restore destination: %w", err)
+	}
+	defer out.Close()
+
+	for _, hash := range manifest.Chunks {
+		path := chunkPath(cfg.BackupDirPath, hash)
+		chunk, err := afero.ReadFile(fsys, path)
+		if err != nil {
+			return fmt.Errorf("failed to read chunk %s: %w", hash, err)
+		}
+		if _, err := out.Write(chunk); err != nil {
+			return fmt.Errorf("failed to write chunk %s to %s: %w", hash, dest, err)
+		}
+	}
+
+	if err := fsys.Chmod(dest, os.FileMode(manifest.Mode)); err != nil {
+		return fmt.Errorf("failed to restore file permissions: %w", err)
+	}
+	if err := fsys.Chtimes(dest, time.Now(), manifest.ModTime); err != nil {
+		return fmt.Errorf("failed to restore modification time: %w", err)
+	}
+
+	return nil
+}
+
+// PruneUnreferenced walks every ChunkManifest under
+// <cfg.BackupDirPath>/snapshots/ to build the set of chunk hashes still in
+// use, then removes any chunk under <cfg.BackupDirPath>/data/ that no
+// manifest references. It returns the number of chunks removed.
+// Architecture: Core Functions - Backup Management - PruneUnreferenced
+func PruneUnreferenced(cfg *Config) (int, error) {
+	fsys := cfg.fs()
+
+	referenced := map[string]bool{}
+	snapshotsDir := filepath.Join(cfg.BackupDirPath, cdcSnapshotsDirName)
+
+	if err := walkManifests(fsys, snapshotsDir, func(manifest *ChunkManifest) {
+		for _, hash := range manifest.Chunks {
+			referenced[hash] = true
+		}
+	}); err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	dataDir := filepath.Join(cfg.BackupDirPath, cdcDataDirName)
+	shards, err := backupDirEntries(fsys, dataDir)
+	if err != nil {
+		return 0, err
+	}
+	for _, shard := range shards {
+		if !shard.IsDir() {
+			continue
+		}
+		shardDir := filepath.Join(dataDir, shard.Name())
+		chunkFiles, err := backupDirEntries(fsys, shardDir)
+		if err != nil {
+			return 0, err
+		}
+		for _, chunkFile := range chunkFiles {
+			if chunkFile.IsDir() || referenced[chunkFile.Name()] {
+				continue
+			}
+			if err := fsys.Remove(filepath.Join(shardDir, chunkFile.Name())); err != nil {
+				return removed, fmt.Errorf("failed to remove unreferenced chunk %s: %w", chunkFile.Name(), err)
+			}
+			removed++
+		}
+	}
+
+	return removed, nil
+}
+
+// walkManifests calls fn for every ChunkManifest found under dir,
+// recursing into subdirectories the same way plain backups mirror the
+// source tree. A missing dir is not an error.
+func walkManifests(fsys afero.Fs, dir string, fn func(*ChunkManifest)) error {
+	entries, err := backupDirEntries(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			if err := walkManifests(fsys, path, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if !strings.HasSuffix(entry.Name(), cdcManifestSuffix) {
+			continue
+		}
+
+		manifest, err := readChunkManifest(fsys, path)
+		if err != nil {
+			return err
+		}
+		fn(manifest)
+	}
+	return nil
+}
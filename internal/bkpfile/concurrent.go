@@ -0,0 +1,152 @@
+package bkpfile
+
+import (
+	"os"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// Observer receives per-file progress events as CreateBackups works
+// through a batch, mirroring the status callbacks tools like restic and
+// rclone use to drive their progress displays.
+// Architecture: Core Types - Observer
+type Observer interface {
+	// OnStart is called once a file's backup begins. size is the source
+	// file's size in bytes, or 0 if it could not be determined (e.g. the
+	// file disappeared between listing and stat).
+	OnStart(path string, size int64)
+
+	// OnProgress reports bytesDone copied so far for path. The copy path
+	// CreateBackups uses today is not chunked, so callers should expect
+	// at most one OnProgress call per file, with bytesDone equal to the
+	// file's full size, reported once the copy completes rather than
+	// incrementally.
+	OnProgress(path string, bytesDone int64)
+
+	// OnComplete is called once a file's backup finishes, successfully or
+	// not; result.Err is nil on success.
+	OnComplete(path string, result BackupResult)
+
+	// OnError is called in addition to OnComplete when a file's backup
+	// fails, so an observer that only cares about failures doesn't need
+	// to inspect every OnComplete result.
+	OnError(path string, err error)
+}
+
+// NopObserver implements Observer with no-op methods, for callers that
+// don't need progress reporting.
+// Architecture: Core Types - NopObserver
+type NopObserver struct{}
+
+func (NopObserver) OnStart(path string, size int64)            {}
+func (NopObserver) OnProgress(path string, bytesDone int64)     {}
+func (NopObserver) OnComplete(path string, result BackupResult) {}
+func (NopObserver) OnError(path string, err error)              {}
+
+// BackupResult is the outcome of backing up a single file as part of a
+// CreateBackups batch.
+// Architecture: Data Objects - BackupResult
+type BackupResult struct {
+	// Path is the source file path that was backed up.
+	Path string
+
+	// Bytes is the source file's size at backup time, 0 if it couldn't
+	// be determined before the failure that prevented backup.
+	Bytes int64
+
+	// Duration is how long this file's backup took.
+	Duration time.Duration
+
+	// Err is the error CreateBackupWithTags returned, if any. A
+	// *BackupError carrying a success status code (StatusCreatedBackup or
+	// StatusFileIsIdenticalToExistingBackup) is folded into a nil Err, so
+	// "backed up" and "skipped, already identical" both read as success
+	// and are not told apart here.
+	Err error
+}
+
+// CreateBackups backs up each of paths, fanning work across a pool of
+// concurrency workers (runtime.NumCPU() if concurrency <= 0) and
+// reporting progress for each file through cfg.Observer (NopObserver if
+// unset). concurrency == 1 runs paths serially, in order, calling
+// CreateBackupWithTags exactly as a single-file backup would. Each file
+// goes through CreateBackupWithTags independently, so the identical-file
+// short-circuit and disk-full detection it already does still apply
+// per-file, and one file's error never aborts the rest of the batch: it
+// is recorded in that file's BackupResult and iteration continues.
+// CreateBackups itself always returns a nil error (an empty paths yields
+// an empty slice); per-file failures live in the returned slice.
+// Architecture: Core Functions - Backup Management - CreateBackups
+func CreateBackups(cfg *Config, paths []string, note string, dryRun bool, concurrency int) ([]BackupResult, error) {
+	observer := cfg.observer()
+
+	if concurrency <= 0 {
+		concurrency = runtime.NumCPU()
+	}
+	if concurrency > len(paths) {
+		concurrency = len(paths)
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]BackupResult, len(paths))
+	jobs := make(chan int)
+
+	backupOne := func(i int) {
+		path := paths[i]
+		var size int64
+		if info, err := os.Stat(path); err == nil {
+			size = info.Size()
+		}
+		observer.OnStart(path, size)
+
+		start := timeNow()
+		err := CreateBackupWithTags(cfg, path, note, nil, dryRun)
+		duration := timeNow().Sub(start)
+
+		result := BackupResult{Path: path, Bytes: size, Duration: duration, Err: err}
+		// CreateBackupWithTags reports success through a *BackupError
+		// with a success status code rather than a nil error; only a
+		// genuine failure status is worth an OnError callback.
+		if backupErr, ok := err.(*BackupError); ok {
+			isSuccess := backupErr.StatusCode == cfg.StatusCreatedBackup ||
+				backupErr.StatusCode == cfg.StatusFileIsIdenticalToExistingBackup
+			if isSuccess {
+				result.Err = nil
+			}
+		}
+		observer.OnProgress(path, size)
+		if result.Err != nil {
+			observer.OnError(path, result.Err)
+		}
+		observer.OnComplete(path, result)
+		results[i] = result
+	}
+
+	if concurrency == 1 {
+		for i := range paths {
+			backupOne(i)
+		}
+		return results, nil
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				backupOne(i)
+			}
+		}()
+	}
+	for i := range paths {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results, nil
+}
@@ -0,0 +1,124 @@
+package bkpfile
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+// recordingObserver collects every callback it receives, guarded by a
+// mutex since CreateBackups drives it from multiple worker goroutines.
+type recordingObserver struct {
+	mu        sync.Mutex
+	started   []string
+	completed []BackupResult
+	errored   []string
+}
+
+func (o *recordingObserver) OnStart(path string, size int64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started = append(o.started, path)
+}
+
+func (o *recordingObserver) OnProgress(path string, bytesDone int64) {}
+
+func (o *recordingObserver) OnComplete(path string, result BackupResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.completed = append(o.completed, result)
+}
+
+func (o *recordingObserver) OnError(path string, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.errored = append(o.errored, path)
+}
+
+func TestCreateBackupsConcurrent(t *testing.T) {
+	env, _ := newTestEnv(t)
+
+	const fileCount = 5
+	var paths []string
+	for i := 0; i < fileCount; i++ {
+		name := fmt.Sprintf("file%d.txt", i)
+		env.CreateFile(name, fmt.Sprintf("content %d", i))
+		paths = append(paths, name)
+	}
+
+	cfg, _, err := env.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	obs := &recordingObserver{}
+	cfg.Observer = obs
+
+	results, err := CreateBackups(cfg, paths, "batch", false, 3)
+	if err != nil {
+		t.Fatalf("CreateBackups() error: %v", err)
+	}
+	if len(results) != fileCount {
+		t.Fatalf("CreateBackups() returned %d results, want %d", len(results), fileCount)
+	}
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("CreateBackups() result for %s: %v", result.Path, result.Err)
+		}
+	}
+
+	if len(obs.started) != fileCount {
+		t.Errorf("observer saw %d OnStart calls, want %d", len(obs.started), fileCount)
+	}
+	if len(obs.completed) != fileCount {
+		t.Errorf("observer saw %d OnComplete calls, want %d", len(obs.completed), fileCount)
+	}
+	if len(obs.errored) != 0 {
+		t.Errorf("observer saw unexpected OnError calls: %v", obs.errored)
+	}
+
+	for _, path := range paths {
+		backups, err := ListBackups(cfg.BackupDirPath, path)
+		if err != nil {
+			t.Fatalf("ListBackups(%s) error: %v", path, err)
+		}
+		if len(backups) != 1 {
+			t.Errorf("ListBackups(%s) = %d backups, want 1", path, len(backups))
+		}
+	}
+}
+
+func TestCreateBackupsSerialMatchesConcurrencyOne(t *testing.T) {
+	env, _ := newTestEnv(t)
+
+	env.CreateFile("a.txt", "a")
+	env.CreateFile("missing-perm.txt", "b")
+
+	cfg, _, err := env.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	results, err := CreateBackups(cfg, []string{"a.txt", "no-such-file.txt"}, "", false, 1)
+	if err != nil {
+		t.Fatalf("CreateBackups() error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("CreateBackups() returned %d results, want 2", len(results))
+	}
+	if results[0].Err != nil {
+		t.Errorf("results[0].Err = %v, want nil", results[0].Err)
+	}
+	if results[1].Err == nil {
+		t.Error("results[1].Err = nil, want an error for the missing file")
+	}
+
+	// The missing file's failure must not have stopped a.txt from backing up.
+	backups, err := ListBackups(cfg.BackupDirPath, "a.txt")
+	if err != nil {
+		t.Fatalf("ListBackups() error: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Errorf("ListBackups(a.txt) = %d backups, want 1", len(backups))
+	}
+}
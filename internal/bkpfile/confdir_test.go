@@ -0,0 +1,91 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigMergesConfDir(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-confdir-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	baseConfig := "backup_dir_path: /base/backups\nstatus_disk_full: 1\n"
+	if err := os.WriteFile(filepath.Join(tmpDir, ".bkpfile.yml"), []byte(baseConfig), 0644); err != nil {
+		t.Fatalf("Failed to write base config: %v", err)
+	}
+
+	confDir := filepath.Join(tmpDir, confDirName)
+	if err := os.MkdirAll(confDir, 0755); err != nil {
+		t.Fatalf("Failed to create conf.d dir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(confDir, "10-disk-full.yml"), []byte("status_disk_full: 2\n"), 0644); err != nil {
+		t.Fatalf("Failed to write drop-in: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(confDir, "20-disk-full.yml"), []byte("status_disk_full: 3\n"), 0644); err != nil {
+		t.Fatalf("Failed to write drop-in: %v", err)
+	}
+
+	cfg, _, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.BackupDirPath != "/base/backups" {
+		t.Errorf("BackupDirPath = %q, want %q (untouched by drop-ins)", cfg.BackupDirPath, "/base/backups")
+	}
+	if cfg.StatusDiskFull != 3 {
+		t.Errorf("StatusDiskFull = %d, want 3 (last drop-in in lexical order wins)", cfg.StatusDiskFull)
+	}
+}
+
+func TestResolveConfigValuesAttributesConfDirSource(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-confdir-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	origDir, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get working directory: %v", err)
+	}
+	defer os.Chdir(origDir)
+
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to chdir into temp dir: %v", err)
+	}
+
+	baseConfig := "backup_dir_path: /base/backups\n"
+	if err := os.WriteFile(".bkpfile.yml", []byte(baseConfig), 0644); err != nil {
+		t.Fatalf("Failed to write base config: %v", err)
+	}
+
+	confDir := confDirName
+	if err := os.MkdirAll(confDir, 0755); err != nil {
+		t.Fatalf("Failed to create conf.d dir: %v", err)
+	}
+	dropInPath := filepath.Join(confDir, "10-backup-dir.yml")
+	if err := os.WriteFile(dropInPath, []byte("backup_dir_path: /override/backups\n"), 0644); err != nil {
+		t.Fatalf("Failed to write drop-in: %v", err)
+	}
+
+	configValues, _, err := ResolveConfigValues()
+	if err != nil {
+		t.Fatalf("ResolveConfigValues() error = %v", err)
+	}
+
+	idx := findConfigValueIndex(configValues, "backup_dir_path")
+	if idx < 0 {
+		t.Fatalf("backup_dir_path not found in resolved config values")
+	}
+	if configValues[idx].Value != "/override/backups" {
+		t.Errorf("backup_dir_path value = %q, want %q", configValues[idx].Value, "/override/backups")
+	}
+	if configValues[idx].Source != dropInPath {
+		t.Errorf("backup_dir_path source = %q, want %q", configValues[idx].Source, dropInPath)
+	}
+}
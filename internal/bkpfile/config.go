@@ -4,11 +4,421 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"bkpfile/internal/diag"
+
+	"github.com/expr-lang/expr"
+	"github.com/spf13/afero"
 	"gopkg.in/yaml.v3"
 )
 
+// confDirName is the name of the drop-in directory scanned alongside each
+// resolved .bkpfile.yml location (e.g. ./.bkpfile.conf.d/, ~/.bkpfile.conf.d/).
+const confDirName = ".bkpfile.conf.d"
+
+// knownConfigKeys lists every top-level YAML key bkpfile understands.
+// Any other top-level key in a loaded config file produces a warning
+// diagnostic rather than being silently ignored.
+var knownConfigKeys = map[string]bool{
+	"backup_dir_path":                             true,
+	"use_current_dir_name":                        true,
+	"status_created_backup":                       true,
+	"status_failed_to_create_backup_directory":    true,
+	"status_file_is_identical_to_existing_backup": true,
+	"status_file_not_found":                       true,
+	"status_invalid_file_type":                    true,
+	"status_permission_denied":                    true,
+	"status_disk_full":                            true,
+	"status_config_error":                         true,
+	"status_encryption_error":                     true,
+	"status_pruned":                               true,
+	"profiles":                                    true,
+	"encryption":                                  true,
+	"retention":                                   true,
+	"archive_format":                              true,
+	"config_strict_env":                           true,
+	"hash_algorithm":                              true,
+	"storage_mode":                                true,
+	"backup_url":                                  true,
+}
+
+// deprecatedConfigKeys maps a deprecated top-level key to the key that
+// replaced it, used to emit a warning diagnostic pointing users at the
+// current name instead of silently honoring (or ignoring) the old one.
+var deprecatedConfigKeys = map[string]string{
+	"backup_dir": "backup_dir_path",
+}
+
+// checkUnknownAndDeprecatedKeys returns warning diagnostics for any
+// top-level key in yamlData that bkpfile doesn't recognize, and for any
+// key that has been renamed.
+func checkUnknownAndDeprecatedKeys(path string, yamlData map[string]interface{}) diag.Diagnostics {
+	var diags diag.Diagnostics
+	for key := range yamlData {
+		if replacement, deprecated := deprecatedConfigKeys[key]; deprecated {
+			diags = diags.Extend(diag.Warningf(path, "%q is deprecated, use %q instead", key, replacement))
+			continue
+		}
+		if !knownConfigKeys[key] {
+			diags = diags.Extend(diag.Warningf(path, "unknown configuration key %q", key))
+		}
+	}
+	return diags
+}
+
+// confDirFor returns the conf.d drop-in directory sibling to a resolved
+// config path such as "./.bkpfile.yml" or "/home/user/.bkpfile.yml".
+func confDirFor(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), confDirName)
+}
+
+// dropInFiles returns the *.yml files directly inside dir in lexical order,
+// so callers can merge drop-in config files in a deterministic sequence.
+// A missing or unreadable dir simply yields no files.
+func dropInFiles(fsys afero.Fs, dir string) []string {
+	entries, err := afero.ReadDir(fsys, dir)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yml") {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+// configField describes how one top-level YAML key is merged into a
+// Config and displayed by DisplayConfig. LoadConfig and
+// ResolveConfigValues both drive off this registry instead of repeating
+// an `if _, exists := yamlData[...]` block per field, so adding a field
+// is one entry here rather than matching edits in three places.
+type configField struct {
+	// yamlKey is the top-level YAML key this field is read from.
+	yamlKey string
+
+	// displayName is the name ResolveConfigValues/DisplayConfig show for
+	// this field. It equals yamlKey except for fields that display a
+	// value nested under the key, such as "encryption.enabled".
+	displayName string
+
+	// apply copies this field's parsed value from src onto dst. It
+	// returns a non-fatal error (e.g. a failed home-dir expansion) that
+	// callers surface as a warning diagnostic rather than failing the load.
+	apply func(dst *Config, src *Config) error
+
+	// format renders cfg's current value for this field the way
+	// ResolveConfigValues/DisplayConfig display it.
+	format func(cfg *Config) string
+}
+
+// configFields is the schema LoadConfig and ResolveConfigValues merge
+// and display config keys from. Order controls the sequence DisplayConfig
+// lists default values in.
+var configFields = []configField{
+	{
+		yamlKey:     "backup_dir_path",
+		displayName: "backup_dir_path",
+		apply: func(dst, src *Config) error {
+			if src.BackupDirPath == "" {
+				return nil
+			}
+			path, err := expandHomePath(src.BackupDirPath)
+			dst.BackupDirPath = path
+			return err
+		},
+		format: func(cfg *Config) string { return cfg.BackupDirPath },
+	},
+	{
+		yamlKey:     "status_config_error",
+		displayName: "status_config_error",
+		apply:       func(dst, src *Config) error { dst.StatusConfigError = src.StatusConfigError; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusConfigError) },
+	},
+	{
+		yamlKey:     "status_created_backup",
+		displayName: "status_created_backup",
+		apply:       func(dst, src *Config) error { dst.StatusCreatedBackup = src.StatusCreatedBackup; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusCreatedBackup) },
+	},
+	{
+		yamlKey:     "status_disk_full",
+		displayName: "status_disk_full",
+		apply:       func(dst, src *Config) error { dst.StatusDiskFull = src.StatusDiskFull; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusDiskFull) },
+	},
+	{
+		yamlKey:     "status_failed_to_create_backup_directory",
+		displayName: "status_failed_to_create_backup_directory",
+		apply: func(dst, src *Config) error {
+			dst.StatusFailedToCreateBackupDirectory = src.StatusFailedToCreateBackupDirectory
+			return nil
+		},
+		format: func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusFailedToCreateBackupDirectory) },
+	},
+	{
+		yamlKey:     "status_file_is_identical_to_existing_backup",
+		displayName: "status_file_is_identical_to_existing_backup",
+		apply: func(dst, src *Config) error {
+			dst.StatusFileIsIdenticalToExistingBackup = src.StatusFileIsIdenticalToExistingBackup
+			return nil
+		},
+		format: func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusFileIsIdenticalToExistingBackup) },
+	},
+	{
+		yamlKey:     "status_file_not_found",
+		displayName: "status_file_not_found",
+		apply:       func(dst, src *Config) error { dst.StatusFileNotFound = src.StatusFileNotFound; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusFileNotFound) },
+	},
+	{
+		yamlKey:     "status_invalid_file_type",
+		displayName: "status_invalid_file_type",
+		apply:       func(dst, src *Config) error { dst.StatusInvalidFileType = src.StatusInvalidFileType; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusInvalidFileType) },
+	},
+	{
+		yamlKey:     "status_permission_denied",
+		displayName: "status_permission_denied",
+		apply:       func(dst, src *Config) error { dst.StatusPermissionDenied = src.StatusPermissionDenied; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusPermissionDenied) },
+	},
+	{
+		yamlKey:     "use_current_dir_name",
+		displayName: "use_current_dir_name",
+		apply:       func(dst, src *Config) error { dst.UseCurrentDirName = src.UseCurrentDirName; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%t", cfg.UseCurrentDirName) },
+	},
+	{
+		yamlKey:     "status_encryption_error",
+		displayName: "status_encryption_error",
+		apply:       func(dst, src *Config) error { dst.StatusEncryptionError = src.StatusEncryptionError; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusEncryptionError) },
+	},
+	{
+		yamlKey:     "encryption",
+		displayName: "encryption.mode",
+		apply:       func(dst, src *Config) error { dst.Encryption = src.Encryption; return nil },
+		format:      func(cfg *Config) string { return cfg.Encryption.mode() },
+	},
+	{
+		yamlKey:     "status_pruned",
+		displayName: "status_pruned",
+		apply:       func(dst, src *Config) error { dst.StatusPruned = src.StatusPruned; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%d", cfg.StatusPruned) },
+	},
+	{
+		yamlKey:     "retention",
+		displayName: "",
+		apply:       func(dst, src *Config) error { dst.Retention = src.Retention; return nil },
+		format:      func(cfg *Config) string { return "" },
+	},
+	{
+		yamlKey:     "archive_format",
+		displayName: "archive_format",
+		apply:       func(dst, src *Config) error { dst.ArchiveFormat = src.ArchiveFormat; return nil },
+		format:      func(cfg *Config) string { return cfg.ArchiveFormat },
+	},
+	{
+		yamlKey:     "profiles",
+		displayName: "",
+		apply:       func(dst, src *Config) error { dst.Profiles = src.Profiles; return nil },
+		format:      func(cfg *Config) string { return "" },
+	},
+	{
+		yamlKey:     "config_strict_env",
+		displayName: "config_strict_env",
+		apply:       func(dst, src *Config) error { dst.ConfigStrictEnv = src.ConfigStrictEnv; return nil },
+		format:      func(cfg *Config) string { return fmt.Sprintf("%t", cfg.ConfigStrictEnv) },
+	},
+	{
+		yamlKey:     "hash_algorithm",
+		displayName: "hash_algorithm",
+		apply:       func(dst, src *Config) error { dst.HashAlgorithm = src.HashAlgorithm; return nil },
+		format:      func(cfg *Config) string { return cfg.HashAlgorithm },
+	},
+	{
+		yamlKey:     "storage_mode",
+		displayName: "storage_mode",
+		apply:       func(dst, src *Config) error { dst.StorageMode = src.StorageMode; return nil },
+		format:      func(cfg *Config) string { return cfg.StorageMode },
+	},
+	{
+		yamlKey:     "backup_url",
+		displayName: "backup_url",
+		apply:       func(dst, src *Config) error { dst.BackupURL = src.BackupURL; return nil },
+		format:      func(cfg *Config) string { return cfg.BackupURL },
+	},
+}
+
+// findConfigField returns the configFields entry for yamlKey, or nil.
+func findConfigField(yamlKey string) *configField {
+	for i := range configFields {
+		if configFields[i].yamlKey == yamlKey {
+			return &configFields[i]
+		}
+	}
+	return nil
+}
+
+// expandHomePath expands a leading ~/ in path using the user's home
+// directory, returning path unchanged (and the UserHomeDir error, if
+// any) when expansion fails.
+func expandHomePath(path string) (string, error) {
+	if !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path, fmt.Errorf("failed to expand home directory in backup_dir_path: %w", err)
+	}
+	return filepath.Join(homeDir, path[2:]), nil
+}
+
+// configGroup is the single effective value a config file and its conf.d
+// drop-ins contribute for each field they set: drop-ins are applied in
+// lexical order over the file, so the last one to touch a field wins.
+// loadConfigGroup computes this once; LoadConfig and ResolveConfigValues
+// each decide separately whether to adopt it (the earliest group in
+// search-path order that sets a field wins overall).
+type configGroup struct {
+	// cfg holds the merged literal values this group sets.
+	cfg *Config
+	// exprs holds, by yamlKey, the last `expr:` value seen in this group.
+	exprs map[string]configExpr
+	// sources holds, by yamlKey, the path that last set this field
+	// within the group (the main file, or a conf.d drop-in).
+	sources map[string]string
+}
+
+// loadConfigGroup parses the config file at configPath together with its
+// conf.d drop-in directory, and returns the group's combined result:
+// configFields.apply is used to merge each file's explicitly-set keys,
+// later drop-ins (in lexical order) overriding earlier ones.
+func loadConfigGroup(fsys afero.Fs, configPath string) (*configGroup, diag.Diagnostics, error) {
+	var diags diag.Diagnostics
+	group := newConfigGroup()
+
+	data, err := afero.ReadFile(fsys, configPath)
+	if err != nil {
+		return nil, diags, err
+	}
+	fileDiags, err := applyConfigFileToGroup(group, configPath, data)
+	diags = diags.Extend(fileDiags)
+	if err != nil {
+		return nil, diags, err
+	}
+
+	confDir := confDirFor(configPath)
+	for _, name := range dropInFiles(fsys, confDir) {
+		dropInPath := filepath.Join(confDir, name)
+
+		data, err := afero.ReadFile(fsys, dropInPath)
+		if err != nil {
+			diags = diags.Extend(diag.Warningf(dropInPath, "failed to read drop-in config: %v", err))
+			continue
+		}
+		fileDiags, err := applyConfigFileToGroup(group, dropInPath, data)
+		diags = diags.Extend(fileDiags)
+		if err != nil {
+			diags = diags.Extend(diag.Warningf(dropInPath, "failed to parse drop-in config: %v", err))
+			continue
+		}
+	}
+
+	return group, diags, nil
+}
+
+// loadConfigGroupFromMemory builds a configGroup from a single buffer
+// registered with SetInMemoryConfig, bypassing both the filesystem and
+// conf.d drop-ins (an in-memory source has no directory to scan).
+func loadConfigGroupFromMemory(name string) (*configGroup, diag.Diagnostics, error) {
+	var diags diag.Diagnostics
+
+	data, ok := GetInMemoryConfig(name)
+	if !ok {
+		return nil, diags, fmt.Errorf("no in-memory config registered under name %q", name)
+	}
+
+	group := newConfigGroup()
+	fileDiags, err := applyConfigFileToGroup(group, InMemoryConfigPath+name, data)
+	diags = diags.Extend(fileDiags)
+	if err != nil {
+		return nil, diags, err
+	}
+	return group, diags, nil
+}
+
+// newConfigGroup returns an empty configGroup ready for
+// applyConfigFileToGroup to populate.
+func newConfigGroup() *configGroup {
+	return &configGroup{
+		cfg:     &Config{},
+		exprs:   map[string]configExpr{},
+		sources: map[string]string{},
+	}
+}
+
+// applyConfigFileToGroup parses one config file's data (already read from
+// disk or an in-memory buffer) and merges its explicitly-set keys into
+// group, the way loadConfigGroup merges a main file and loadConfigGroup's
+// conf.d drop-in loop merges each drop-in: later calls for the same group
+// override earlier ones field-by-field.
+func applyConfigFileToGroup(group *configGroup, path string, data []byte) (diag.Diagnostics, error) {
+	var diags diag.Diagnostics
+
+	var yamlData map[string]interface{}
+	if err := yaml.Unmarshal(data, &yamlData); err != nil {
+		return diags, err
+	}
+
+	yamlData, err := expandEnvInConfig(yamlData)
+	if err != nil {
+		return diags, fmt.Errorf("%s: %w", path, err)
+	}
+
+	var fileExprs []configExpr
+	yamlData, fileExprs, err = extractConfigExprs(path, yamlData)
+	if err != nil {
+		return diags, err
+	}
+
+	cleanedData, err := yaml.Marshal(yamlData)
+	if err != nil {
+		return diags, err
+	}
+	tempCfg := &Config{}
+	if err := yaml.Unmarshal(cleanedData, tempCfg); err != nil {
+		return diags, err
+	}
+
+	diags = diags.Extend(checkUnknownAndDeprecatedKeys(path, yamlData))
+
+	for _, ce := range fileExprs {
+		group.exprs[ce.field] = ce
+		group.sources[ce.field] = path
+	}
+
+	for _, field := range configFields {
+		if _, exists := yamlData[field.yamlKey]; !exists {
+			continue
+		}
+		if err := field.apply(group.cfg, tempCfg); err != nil {
+			diags = diags.Extend(diag.Warningf(path, "%v", err))
+		}
+		delete(group.exprs, field.yamlKey)
+		group.sources[field.yamlKey] = path
+	}
+
+	return diags, nil
+}
+
 // Config represents the application configuration
 // Architecture: Data Objects - Config
 type Config struct {
@@ -44,6 +454,137 @@ type Config struct {
 
 	// Architecture: Config.StatusConfigError
 	StatusConfigError int `yaml:"status_config_error"`
+
+	// Architecture: Config.StatusEncryptionError
+	StatusEncryptionError int `yaml:"status_encryption_error"`
+
+	// Architecture: Config.StatusPruned
+	StatusPruned int `yaml:"status_pruned"`
+
+	// Profiles lists named recurring backup schedules driven by `bkpfile daemon`
+	// Architecture: Config.Profiles
+	Profiles []Profile `yaml:"profiles"`
+
+	// Encryption controls whether created backups are encrypted at rest
+	// Architecture: Config.Encryption
+	Encryption EncryptionConfig `yaml:"encryption"`
+
+	// Retention controls how `bkpfile.PruneBackups` decides which backups to keep
+	// Architecture: Config.Retention
+	Retention RetentionConfig `yaml:"retention"`
+
+	// ArchiveFormat selects how directory backups are packaged: "none"
+	// (directories are rejected), "tar", "tar.gz", or "zip"
+	// Architecture: Config.ArchiveFormat
+	ArchiveFormat string `yaml:"archive_format"`
+
+	// ConfigStrictEnv controls what happens when a ${VAR}/$VAR reference in
+	// this config file names an environment variable that isn't set: false
+	// (the default) expands it to "", matching os.ExpandEnv; true makes it
+	// a load error instead of a silently empty path.
+	// Architecture: Config.ConfigStrictEnv
+	ConfigStrictEnv bool `yaml:"config_strict_env"`
+
+	// HashAlgorithm selects the hash CreateBackup/CreateBackupWithTime use
+	// for backup metadata sidecars and `bkpfile verify`: "sha256" (the
+	// default), "xxhash", or "blake2b".
+	// Architecture: Config.HashAlgorithm
+	HashAlgorithm string `yaml:"hash_algorithm"`
+
+	// StorageMode selects how CreateBackup/CreateBackupWithTime store a
+	// regular file's content: "" (the default, one full copy per backup)
+	// or "chunked", which delegates to CreateCDCBackup so repeated backups
+	// of the same file only store the chunks that actually changed.
+	// Directory backups are unaffected; they always go through the
+	// archive_format path.
+	// Architecture: Config.StorageMode
+	StorageMode string `yaml:"storage_mode"`
+
+	// BackupURL selects a remote backend for Config.BackupStore instead of
+	// the local directory at BackupDirPath: "s3://bucket/prefix",
+	// "sftp://user@host/path", "webdav://host/path", or "webdavs://..."
+	// for WebDAV over TLS. Left empty (the default), backups are written
+	// directly under BackupDirPath as they always have been. LoadConfigFS
+	// resolves this into Config.BackupStore, fronted by a local disk cache
+	// (see NewCachingBackupStore) so identical-file detection stays cheap.
+	// Architecture: Config.BackupURL
+	BackupURL string `yaml:"backup_url"`
+
+	// exprs holds the compiled `expr:` expressions found for any field
+	// that was written as a map with an "expr" key instead of a literal,
+	// so Resolve can evaluate them without recompiling.
+	// Architecture: Config.exprs
+	exprs []configExpr `yaml:"-"`
+
+	// sources records, by yamlKey, the config file path that set each
+	// explicitly-configured field, for callers that need provenance
+	// without going through ResolveConfigValues. Fields left at their
+	// default have no entry.
+	// Architecture: Config.sources
+	sources map[string]string `yaml:"-"`
+
+	// FS is the filesystem backup and restore I/O is performed against.
+	// It defaults to afero.NewOsFs() (real disk I/O); tests can swap in
+	// afero.NewMemMapFs(), and a deployment can plug in afero.NewBasePathFs
+	// (chroot-style sandboxing) or a remote backend (SFTP, S3).
+	// Architecture: Config.FS
+	FS afero.Fs `yaml:"-"`
+
+	// BackupStore is where backup blobs under BackupDirPath are actually
+	// written and read once CreateBackupWithTime hands off to the store
+	// layer, rather than going through FS directly. It defaults to a
+	// localBackupStore over FS (today's behavior); a deployment can plug
+	// in a remote RemoteBackupFS fronted by NewCachingBackupStore to keep
+	// backups off-machine while still hashing against a warm local cache.
+	// Architecture: Config.BackupStore
+	BackupStore RemoteBackupFS `yaml:"-"`
+
+	// Observer receives per-file progress events from CreateBackups. It
+	// defaults to NopObserver for a Config without one configured; a
+	// caller driving a status display (the CLI's --json mode, a future
+	// TUI) plugs in its own Observer instead.
+	// Architecture: Config.Observer
+	Observer Observer `yaml:"-"`
+}
+
+// Sources returns a copy of the config file paths that set each
+// explicitly-configured field of cfg, keyed by yamlKey. A field left at
+// its default value has no entry.
+// Architecture: Core Functions - Configuration Management - Sources
+func (cfg *Config) Sources() map[string]string {
+	sources := make(map[string]string, len(cfg.sources))
+	for k, v := range cfg.sources {
+		sources[k] = v
+	}
+	return sources
+}
+
+// fs returns cfg.FS, falling back to afero.NewOsFs() for a Config built
+// without going through DefaultConfig/LoadConfig.
+func (cfg *Config) fs() afero.Fs {
+	if cfg.FS != nil {
+		return cfg.FS
+	}
+	return afero.NewOsFs()
+}
+
+// store returns cfg.BackupStore, falling back to a localBackupStore rooted
+// at cfg.BackupDirPath on cfg.fs() for a Config without a remote store
+// configured.
+func (cfg *Config) store() RemoteBackupFS {
+	if cfg.BackupStore != nil {
+		return cfg.BackupStore
+	}
+	return newLocalBackupStore(cfg.fs(), cfg.BackupDirPath)
+}
+
+// observer returns cfg.Observer, falling back to NopObserver for a Config
+// without one configured.
+func (cfg *Config) observer() Observer {
+	if cfg.Observer != nil {
+		return cfg.Observer
+	}
+	return NopObserver{}
 }
 
 // ConfigValue represents a configuration parameter with its computed value and source
@@ -60,6 +601,11 @@ type ConfigValue struct {
 	// Source is the source file path or "default" for default values
 	// Architecture: ConfigValue.Source
 	Source string
+
+	// RawExpr is the `expr:` source text this value was evaluated from,
+	// or "" if the value was a literal.
+	// Architecture: ConfigValue.RawExpr
+	RawExpr string
 }
 
 // DefaultConfig creates a new Config with default values
@@ -75,21 +621,35 @@ func DefaultConfig() *Config {
 		StatusFileNotFound:                    20,
 		StatusInvalidFileType:                 21,
 		StatusPermissionDenied:                22,
+		StatusEncryptionError:                 32,
+		StatusPruned:                          33,
 		UseCurrentDirName:                     true,
+		ArchiveFormat:                         "none",
+		HashAlgorithm:                         HashAlgorithmSHA256,
+		FS:                                    afero.NewOsFs(),
 	}
 }
 
 // GetConfigSearchPath returns the list of configuration file paths to search
 // Architecture: Core Functions - Configuration Management - GetConfigSearchPath
 func GetConfigSearchPath() []string {
-	// Read BKPFILE_CONFIG environment variable
-	envConfig := os.Getenv("BKPFILE_CONFIG")
+	// GetConfigPath takes precedence over BKPFILE_CONFIG, so embedders
+	// can configure bkpfile without touching the process environment.
+	envConfig := GetConfigPath()
+	if envConfig == "" {
+		envConfig = os.Getenv("BKPFILE_CONFIG")
+	}
 
 	var paths []string
-	if envConfig != "" {
+	switch {
+	case strings.HasPrefix(envConfig, InMemoryConfigPath):
+		// In-memory mode is a single buffer, not a colon-separated list:
+		// the name itself could legitimately contain ':'.
+		paths = []string{envConfig}
+	case envConfig != "":
 		// Split on colon to get path list
 		paths = strings.Split(envConfig, ":")
-	} else {
+	default:
 		// Use hard-coded default path list
 		paths = []string{"./.bkpfile.yml", "~/.bkpfile.yml"}
 	}
@@ -106,6 +666,99 @@ func GetConfigSearchPath() []string {
 	return paths
 }
 
+// ConfigFileAlreadyExistsError indicates SafeWriteConfig refused to
+// overwrite a config file that already exists at the resolved path.
+// Architecture: Data Objects - ConfigFileAlreadyExistsError
+type ConfigFileAlreadyExistsError struct {
+	// Path is the config file that already exists
+	// Architecture: ConfigFileAlreadyExistsError.Path
+	Path string
+}
+
+func (e *ConfigFileAlreadyExistsError) Error() string {
+	return fmt.Sprintf("config file already exists: %s", e.Path)
+}
+
+// MissingConfigurationError indicates no configuration path could be
+// resolved: no explicit path was given and GetConfigSearchPath returned
+// nothing to fall back to.
+// Architecture: Data Objects - MissingConfigurationError
+type MissingConfigurationError struct{}
+
+func (e *MissingConfigurationError) Error() string {
+	return "no configuration path could be resolved"
+}
+
+// ResolveConfigPath resolves path the way LoadConfig resolves search-path
+// entries: expanding a leading ~/ to the user's home directory, and — when
+// path is empty — falling back to the first entry in GetConfigSearchPath().
+// Architecture: Core Functions - Configuration Management - ResolveConfigPath
+func ResolveConfigPath(path string) (string, error) {
+	if path == "" {
+		paths := GetConfigSearchPath()
+		if len(paths) == 0 {
+			return "", &MissingConfigurationError{}
+		}
+		path = paths[0]
+	}
+
+	if strings.HasPrefix(path, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to expand home directory: %w", err)
+		}
+		path = filepath.Join(homeDir, path[2:])
+	}
+
+	return path, nil
+}
+
+// WriteConfig marshals cfg to YAML and writes it to path, creating any
+// missing parent directories. When path is empty, it writes to the first
+// entry in GetConfigSearchPath(). An existing file at the resolved path is
+// overwritten; use SafeWriteConfig to refuse that instead.
+// Architecture: Core Functions - Configuration Management - WriteConfig
+func (cfg *Config) WriteConfig(path string) error {
+	resolvedPath, err := ResolveConfigPath(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(resolvedPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	if err := os.WriteFile(resolvedPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	return nil
+}
+
+// SafeWriteConfig is like WriteConfig but returns a
+// *ConfigFileAlreadyExistsError instead of overwriting a file that already
+// exists at the resolved path.
+// Architecture: Core Functions - Configuration Management - SafeWriteConfig
+func (cfg *Config) SafeWriteConfig(path string) error {
+	resolvedPath, err := ResolveConfigPath(path)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(resolvedPath); err == nil {
+		return &ConfigFileAlreadyExistsError{Path: resolvedPath}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("failed to check for existing config file: %w", err)
+	}
+
+	return cfg.WriteConfig(resolvedPath)
+}
+
 // findConfigValueIndex returns the index of the config value with the given name
 // Returns -1 if not found
 func findConfigValueIndex(configValues []ConfigValue, name string) int {
@@ -117,278 +770,221 @@ func findConfigValueIndex(configValues []ConfigValue, name string) int {
 	return -1
 }
 
-// DisplayConfig displays computed configuration values and exits
+// DisplayConfig displays computed configuration values and returns any
+// non-fatal diagnostics (unknown keys, deprecated fields, missing files
+// named in BKPFILE_CONFIG) gathered while resolving them.
 // Architecture: Core Functions - Configuration Management - DisplayConfig
-func DisplayConfig() error {
-	// Get configuration search paths
+func DisplayConfig() (diag.Diagnostics, error) {
+	configValues, diags, err := ResolveConfigValues()
+	if err != nil {
+		return diags, err
+	}
+
+	// Display each configuration value with name, computed value, and source
+	for _, cv := range configValues {
+		if cv.RawExpr != "" {
+			fmt.Printf("%s: %s (source: %s, expr: %s)\n", cv.Name, cv.Value, cv.Source, cv.RawExpr)
+			continue
+		}
+		fmt.Printf("%s: %s (source: %s)\n", cv.Name, cv.Value, cv.Source)
+	}
+
+	return diags, nil
+}
+
+// ResolveConfigValues computes the same per-field value/source provenance
+// that DisplayConfig prints, without printing it. It is also used by
+// `bkpfile config export` to record provenance in a bundle's manifest.yml.
+// Architecture: Core Functions - Configuration Management - ResolveConfigValues
+func ResolveConfigValues() ([]ConfigValue, diag.Diagnostics, error) {
+	var diags diag.Diagnostics
+
+	fsys := afero.NewOsFs()
 	searchPaths := GetConfigSearchPath()
+	envConfigured := os.Getenv("BKPFILE_CONFIG") != ""
 
-	// Initialize with default values and track sources
 	defaultCfg := DefaultConfig()
-	configValues := []ConfigValue{
-		{Name: "backup_dir_path", Value: defaultCfg.BackupDirPath, Source: "default"},
-		{Name: "status_config_error", Value: fmt.Sprintf("%d", defaultCfg.StatusConfigError), Source: "default"},
-		{Name: "status_created_backup", Value: fmt.Sprintf("%d", defaultCfg.StatusCreatedBackup), Source: "default"},
-		{Name: "status_disk_full", Value: fmt.Sprintf("%d", defaultCfg.StatusDiskFull), Source: "default"},
-		{Name: "status_failed_to_create_backup_directory", Value: fmt.Sprintf("%d", defaultCfg.StatusFailedToCreateBackupDirectory), Source: "default"},
-		{Name: "status_file_is_identical_to_existing_backup", Value: fmt.Sprintf("%d", defaultCfg.StatusFileIsIdenticalToExistingBackup), Source: "default"},
-		{Name: "status_file_not_found", Value: fmt.Sprintf("%d", defaultCfg.StatusFileNotFound), Source: "default"},
-		{Name: "status_invalid_file_type", Value: fmt.Sprintf("%d", defaultCfg.StatusInvalidFileType), Source: "default"},
-		{Name: "status_permission_denied", Value: fmt.Sprintf("%d", defaultCfg.StatusPermissionDenied), Source: "default"},
-		{Name: "use_current_dir_name", Value: fmt.Sprintf("%t", defaultCfg.UseCurrentDirName), Source: "default"},
-	}
-
-	// Process configuration files in order with precedence rules
+	configValues := make([]ConfigValue, 0, len(configFields))
+	for _, field := range configFields {
+		if field.displayName == "" {
+			continue
+		}
+		configValues = append(configValues, ConfigValue{
+			Name:   field.displayName,
+			Value:  field.format(defaultCfg),
+			Source: "default",
+		})
+	}
+
 	for _, configPath := range searchPaths {
-		// Store original path for source display
 		originalPath := configPath
-
-		// Handle relative paths by resolving them relative to current directory
 		if !filepath.IsAbs(configPath) {
 			configPath = filepath.Join(".", configPath)
-			// Update original path to include ./ prefix for relative paths
 			if !strings.HasPrefix(originalPath, "./") && !strings.HasPrefix(originalPath, "/") {
 				originalPath = "./" + originalPath
 			}
 		}
 
-		// Check if config file exists
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		if _, err := fsys.Stat(configPath); os.IsNotExist(err) {
+			if envConfigured {
+				diags = diags.Extend(diag.Warningf(originalPath, "configured file does not exist"))
+			}
 			continue
 		}
 
-		// Read config file
-		data, err := os.ReadFile(configPath)
+		group, groupDiags, err := loadConfigGroup(fsys, configPath)
+		diags = diags.Extend(groupDiags)
 		if err != nil {
-			return fmt.Errorf("failed to read config file %s: %w", configPath, err)
-		}
-
-		// Parse YAML into a map to check which fields are actually set
-		var yamlData map[string]interface{}
-		if err := yaml.Unmarshal(data, &yamlData); err != nil {
-			return fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+			return nil, diags, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
 		}
 
-		// Parse YAML into a temporary config
-		tempCfg := &Config{}
-		if err := yaml.Unmarshal(data, tempCfg); err != nil {
-			return fmt.Errorf("failed to parse config file %s: %w", configPath, err)
-		}
-
-		// Update configuration values with source tracking (earlier files take precedence)
-		if _, exists := yamlData["backup_dir_path"]; exists && tempCfg.BackupDirPath != "" {
-			// Expand home directory in backup path
-			backupPath := tempCfg.BackupDirPath
-			if strings.HasPrefix(backupPath, "~/") {
-				if homeDir, err := os.UserHomeDir(); err == nil {
-					backupPath = filepath.Join(homeDir, backupPath[2:])
-				}
+		for field, ce := range group.exprs {
+			output, err := expr.Run(ce.program, NewConfigContext().exprEnv())
+			if err != nil {
+				return nil, diags, fmt.Errorf("failed to evaluate expr for %q in %s: %w", field, group.sources[field], err)
 			}
-			// Update only if not already set by a previous (higher precedence) file
-			if idx := findConfigValueIndex(configValues, "backup_dir_path"); idx >= 0 && configValues[idx].Source == "default" {
-				configValues[idx].Value = backupPath
-				configValues[idx].Source = originalPath
+			if cf := findConfigField(field); cf != nil && cf.displayName != "" {
+				if idx := findConfigValueIndex(configValues, cf.displayName); idx >= 0 && configValues[idx].Source == "default" {
+					configValues[idx].Value = fmt.Sprintf("%v", output)
+					configValues[idx].Source = displaySource(group.sources[field], originalPath, configPath)
+					configValues[idx].RawExpr = ce.source
+				}
 			}
 		}
 
-		if _, exists := yamlData["use_current_dir_name"]; exists {
-			// Update only if not already set by a previous (higher precedence) file
-			if idx := findConfigValueIndex(configValues, "use_current_dir_name"); idx >= 0 && configValues[idx].Source == "default" {
-				configValues[idx].Value = fmt.Sprintf("%t", tempCfg.UseCurrentDirName)
-				configValues[idx].Source = originalPath
+		for _, field := range configFields {
+			if field.displayName == "" {
+				continue
 			}
-		}
-
-		// Handle status code configuration fields
-		statusFields := []struct {
-			yamlKey string
-			value   int
-		}{
-			{"status_config_error", tempCfg.StatusConfigError},
-			{"status_created_backup", tempCfg.StatusCreatedBackup},
-			{"status_disk_full", tempCfg.StatusDiskFull},
-			{"status_failed_to_create_backup_directory", tempCfg.StatusFailedToCreateBackupDirectory},
-			{"status_file_is_identical_to_existing_backup", tempCfg.StatusFileIsIdenticalToExistingBackup},
-			{"status_file_not_found", tempCfg.StatusFileNotFound},
-			{"status_invalid_file_type", tempCfg.StatusInvalidFileType},
-			{"status_permission_denied", tempCfg.StatusPermissionDenied},
-		}
-
-		for _, field := range statusFields {
-			if _, exists := yamlData[field.yamlKey]; exists {
-				// Update only if not already set by a previous (higher precedence) file
-				if idx := findConfigValueIndex(configValues, field.yamlKey); idx >= 0 && configValues[idx].Source == "default" {
-					configValues[idx].Value = fmt.Sprintf("%d", field.value)
-					configValues[idx].Source = originalPath
-				}
+			source, set := group.sources[field.yamlKey]
+			if !set {
+				continue
+			}
+			if idx := findConfigValueIndex(configValues, field.displayName); idx >= 0 && configValues[idx].Source == "default" {
+				configValues[idx].Value = field.format(group.cfg)
+				configValues[idx].Source = displaySource(source, originalPath, configPath)
 			}
 		}
 	}
 
-	// Display each configuration value with name, computed value, and source
-	for _, cv := range configValues {
-		fmt.Printf("%s: %s (source: %s)\n", cv.Name, cv.Value, cv.Source)
-	}
+	return configValues, diags, nil
+}
 
-	return nil
+// displaySource rewrites a path from loadConfigGroup into the form
+// DisplayConfig shows: the search-path entry's own display form
+// (originalPath) when source is configPath itself (the main file of the
+// group), or the drop-in path unchanged otherwise.
+func displaySource(source, originalPath, configPath string) string {
+	if source == configPath {
+		return originalPath
+	}
+	return source
 }
 
-// LoadConfig loads configuration from YAML files using discovery path or returns default config
+// LoadConfig loads configuration from YAML files using discovery path or
+// returns default config, reading them from the real filesystem. See
+// LoadConfigFS to load from an arbitrary afero.Fs instead (for example an
+// afero.NewMemMapFs() in tests).
 // Architecture: Core Functions - Configuration Management - LoadConfig
-func LoadConfig(root string) (*Config, error) {
+func LoadConfig(root string) (*Config, diag.Diagnostics, error) {
+	return LoadConfigFS(afero.NewOsFs(), root)
+}
+
+// LoadConfigFS is LoadConfig against an explicit afero.Fs instead of the
+// real filesystem. Non-fatal problems (unknown keys, missing files named
+// in BKPFILE_CONFIG, home-dir expansion failures, deprecated field names)
+// are returned as warning diagnostics instead of being swallowed; the
+// error return is reserved for problems that make the config unusable,
+// such as malformed YAML or an invalid retention policy.
+// Architecture: Core Functions - Configuration Management - LoadConfigFS
+func LoadConfigFS(fsys afero.Fs, root string) (*Config, diag.Diagnostics, error) {
 	cfg := DefaultConfig()
+	cfg.FS = fsys
+	cfg.sources = map[string]string{}
+	var diags diag.Diagnostics
 
-	// Get configuration search paths
 	searchPaths := GetConfigSearchPath()
+	envConfigured := GetConfigPath() != "" || os.Getenv("BKPFILE_CONFIG") != ""
 
-	// Process configuration files in order (earlier files take precedence)
-	foundConfig := false
 	for _, configPath := range searchPaths {
-		// Handle relative paths by resolving them relative to root
-		if !filepath.IsAbs(configPath) {
-			configPath = filepath.Join(root, configPath)
-		}
+		originalPath := configPath
 
-		// Check if config file exists
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			continue
-		}
+		var group *configGroup
+		var groupDiags diag.Diagnostics
+		var err error
+		if name, ok := strings.CutPrefix(configPath, InMemoryConfigPath); ok {
+			group, groupDiags, err = loadConfigGroupFromMemory(name)
+		} else {
+			if !filepath.IsAbs(configPath) {
+				configPath = filepath.Join(root, configPath)
+			}
 
-		// Read config file
-		data, err := os.ReadFile(configPath)
-		if err != nil {
-			return nil, err
-		}
+			if _, statErr := fsys.Stat(configPath); os.IsNotExist(statErr) {
+				if envConfigured {
+					diags = diags.Extend(diag.Warningf(originalPath, "configured file does not exist"))
+				}
+				continue
+			}
 
-		// Parse YAML into a map to check which fields are actually set
-		var yamlData map[string]interface{}
-		if err := yaml.Unmarshal(data, &yamlData); err != nil {
-			return nil, err
+			group, groupDiags, err = loadConfigGroup(fsys, configPath)
 		}
-
-		// Parse YAML into a temporary config
-		tempCfg := &Config{}
-		if err := yaml.Unmarshal(data, tempCfg); err != nil {
-			return nil, err
+		diags = diags.Extend(groupDiags)
+		if err != nil {
+			return nil, diags, err
 		}
 
-		// Merge configuration with precedence (only update fields that are explicitly set)
-		if !foundConfig {
-			// First config file found sets values for fields that are explicitly present
-			if _, exists := yamlData["backup_dir_path"]; exists && tempCfg.BackupDirPath != "" {
-				// Expand home directory in backup path
-				backupPath := tempCfg.BackupDirPath
-				if strings.HasPrefix(backupPath, "~/") {
-					if homeDir, err := os.UserHomeDir(); err == nil {
-						backupPath = filepath.Join(homeDir, backupPath[2:])
-					}
-				}
-				cfg.BackupDirPath = backupPath
-			}
-			if _, exists := yamlData["use_current_dir_name"]; exists {
-				cfg.UseCurrentDirName = tempCfg.UseCurrentDirName
+		for field, ce := range group.exprs {
+			if _, already := cfg.sources[field]; already {
+				continue
 			}
+			cfg.exprs = append(cfg.exprs, ce)
+			cfg.sources[field] = group.sources[field]
+		}
 
-			// Handle status code configuration fields
-			if _, exists := yamlData["status_created_backup"]; exists {
-				cfg.StatusCreatedBackup = tempCfg.StatusCreatedBackup
-			}
-			if _, exists := yamlData["status_failed_to_create_backup_directory"]; exists {
-				cfg.StatusFailedToCreateBackupDirectory = tempCfg.StatusFailedToCreateBackupDirectory
-			}
-			if _, exists := yamlData["status_file_is_identical_to_existing_backup"]; exists {
-				cfg.StatusFileIsIdenticalToExistingBackup = tempCfg.StatusFileIsIdenticalToExistingBackup
-			}
-			if _, exists := yamlData["status_file_not_found"]; exists {
-				cfg.StatusFileNotFound = tempCfg.StatusFileNotFound
+		for _, field := range configFields {
+			source, set := group.sources[field.yamlKey]
+			if !set {
+				continue
 			}
-			if _, exists := yamlData["status_invalid_file_type"]; exists {
-				cfg.StatusInvalidFileType = tempCfg.StatusInvalidFileType
+			if _, already := cfg.sources[field.yamlKey]; already {
+				continue
 			}
-			if _, exists := yamlData["status_permission_denied"]; exists {
-				cfg.StatusPermissionDenied = tempCfg.StatusPermissionDenied
+			if err := field.apply(cfg, group.cfg); err != nil {
+				diags = diags.Extend(diag.Warningf(source, "%v", err))
 			}
-			if _, exists := yamlData["status_disk_full"]; exists {
-				cfg.StatusDiskFull = tempCfg.StatusDiskFull
-			}
-			if _, exists := yamlData["status_config_error"]; exists {
-				cfg.StatusConfigError = tempCfg.StatusConfigError
-			}
-
-			foundConfig = true
-		} else {
-			// Subsequent config files only override if the field is explicitly set
-			// For this simple implementation, we'll take the first config found
-			// since we want earlier files to take precedence
+			cfg.sources[field.yamlKey] = source
 		}
 	}
 
-	// Check for .bkpfile.yml file in the root directory
-	if !foundConfig {
-		configPath := filepath.Join(root, ".bkpfile.yml")
-		if _, err := os.Stat(configPath); err == nil {
-			// Read config file
-			data, err := os.ReadFile(configPath)
-			if err != nil {
-				return nil, err
-			}
+	if err := cfg.Retention.validate(); err != nil {
+		return nil, diags, fmt.Errorf("invalid retention config: %w", err)
+	}
 
-			// Parse YAML into a map to check which fields are actually set
-			var yamlData map[string]interface{}
-			if err := yaml.Unmarshal(data, &yamlData); err != nil {
-				return nil, err
-			}
+	if err := cfg.Encryption.validate(); err != nil {
+		return nil, diags, fmt.Errorf("invalid encryption config: %w", err)
+	}
 
-			// Parse YAML into a temporary config
-			tempCfg := &Config{}
-			if err := yaml.Unmarshal(data, tempCfg); err != nil {
-				return nil, err
-			}
+	if err := validateHashAlgorithm(cfg.HashAlgorithm); err != nil {
+		return nil, diags, fmt.Errorf("invalid hash_algorithm: %w", err)
+	}
 
-			// Merge with defaults, only overriding explicitly set fields
-			if _, exists := yamlData["backup_dir_path"]; exists && tempCfg.BackupDirPath != "" {
-				// Expand home directory in backup path
-				backupPath := tempCfg.BackupDirPath
-				if strings.HasPrefix(backupPath, "~/") {
-					if homeDir, err := os.UserHomeDir(); err == nil {
-						backupPath = filepath.Join(homeDir, backupPath[2:])
-					}
-				}
-				cfg.BackupDirPath = backupPath
-			}
-			if _, exists := yamlData["use_current_dir_name"]; exists {
-				cfg.UseCurrentDirName = tempCfg.UseCurrentDirName
-			}
+	if err := validateStorageMode(cfg.StorageMode); err != nil {
+		return nil, diags, fmt.Errorf("invalid storage_mode: %w", err)
+	}
 
-			// Handle status code configuration fields
-			if _, exists := yamlData["status_created_backup"]; exists {
-				cfg.StatusCreatedBackup = tempCfg.StatusCreatedBackup
-			}
-			if _, exists := yamlData["status_failed_to_create_backup_directory"]; exists {
-				cfg.StatusFailedToCreateBackupDirectory = tempCfg.StatusFailedToCreateBackupDirectory
-			}
-			if _, exists := yamlData["status_file_is_identical_to_existing_backup"]; exists {
-				cfg.StatusFileIsIdenticalToExistingBackup = tempCfg.StatusFileIsIdenticalToExistingBackup
-			}
-			if _, exists := yamlData["status_file_not_found"]; exists {
-				cfg.StatusFileNotFound = tempCfg.StatusFileNotFound
-			}
-			if _, exists := yamlData["status_invalid_file_type"]; exists {
-				cfg.StatusInvalidFileType = tempCfg.StatusInvalidFileType
-			}
-			if _, exists := yamlData["status_permission_denied"]; exists {
-				cfg.StatusPermissionDenied = tempCfg.StatusPermissionDenied
-			}
-			if _, exists := yamlData["status_disk_full"]; exists {
-				cfg.StatusDiskFull = tempCfg.StatusDiskFull
-			}
-			if _, exists := yamlData["status_config_error"]; exists {
-				cfg.StatusConfigError = tempCfg.StatusConfigError
-			}
+	if cfg.BackupURL != "" {
+		store, err := newBackupStoreForURL(cfg.BackupURL, cfg.fs(), cfg.BackupDirPath)
+		if err != nil {
+			return nil, diags, fmt.Errorf("invalid backup_url: %w", err)
+		}
+		cfg.BackupStore = store
+	}
 
-			foundConfig = true
+	for _, profile := range cfg.Profiles {
+		if err := validateSchedule(profile.Schedule); err != nil {
+			return nil, diags, fmt.Errorf("invalid schedule for profile %q: %w", profile.Name, err)
 		}
 	}
 
-	return cfg, nil
+	return cfg, diags, nil
 }
@@ -0,0 +1,68 @@
+package bkpfile
+
+import (
+	"sync/atomic"
+
+	"bkpfile/internal/diag"
+
+	"github.com/spf13/afero"
+)
+
+// ConfigProvider holds an atomically-swappable Config snapshot so a
+// long-running process (the daemon, or a batch run spanning several
+// backups) can pick up edited BKPFILE_CONFIG files on SIGHUP without
+// restarting. Reload never mutates the *Config a caller already holds;
+// it builds a new one and swaps it in, so an in-flight backup that took
+// its snapshot via Current() keeps running against the config it started
+// with even if a reload happens mid-run.
+// Architecture: Data Objects - ConfigProvider
+type ConfigProvider struct {
+	fsys afero.Fs
+	root string
+
+	current atomic.Pointer[Config]
+}
+
+// NewConfigProvider loads the initial configuration for root via
+// LoadConfigFS and returns a ConfigProvider wrapping it.
+// Architecture: Core Functions - Configuration Management - NewConfigProvider
+func NewConfigProvider(fsys afero.Fs, root string) (*ConfigProvider, diag.Diagnostics, error) {
+	cfg, diags, err := LoadConfigFS(fsys, root)
+	if err != nil {
+		return nil, diags, err
+	}
+	p := &ConfigProvider{fsys: fsys, root: root}
+	p.current.Store(cfg)
+	return p, diags, nil
+}
+
+// NewConfigProviderFromConfig wraps an already-loaded Config (for example
+// one built with DefaultConfig and then customized by a test) in a
+// ConfigProvider, without re-reading BKPFILE_CONFIG.
+// Architecture: Core Functions - Configuration Management - NewConfigProviderFromConfig
+func NewConfigProviderFromConfig(fsys afero.Fs, root string, cfg *Config) *ConfigProvider {
+	p := &ConfigProvider{fsys: fsys, root: root}
+	p.current.Store(cfg)
+	return p
+}
+
+// Current returns the most recently loaded Config snapshot. It is safe to
+// call concurrently with Reload: Reload swaps in a new *Config rather than
+// mutating the one Current previously returned.
+// Architecture: Core Functions - Configuration Management - Current
+func (p *ConfigProvider) Current() *Config {
+	return p.current.Load()
+}
+
+// Reload re-reads BKPFILE_CONFIG from root and, on success, atomically
+// swaps it in as the new Current() snapshot. On error the previous
+// snapshot is left in place and Current keeps returning it.
+// Architecture: Core Functions - Configuration Management - Reload
+func (p *ConfigProvider) Reload() (diag.Diagnostics, error) {
+	cfg, diags, err := LoadConfigFS(p.fsys, p.root)
+	if err != nil {
+		return diags, err
+	}
+	p.current.Store(cfg)
+	return diags, nil
+}
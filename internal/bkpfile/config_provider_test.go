@@ -0,0 +1,120 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+// TestConfigProviderReload mutates global.yml between two calls to Reload
+// and asserts Current() picks up the new BackupDirPath while the *Config
+// pointer handed out before the reload is left untouched, the same
+// in-flight-safety CreateBackupWithTime and friends rely on elsewhere.
+func TestConfigProviderReload(t *testing.T) {
+	originalEnv := os.Getenv("BKPFILE_CONFIG")
+	defer func() {
+		if originalEnv != "" {
+			os.Setenv("BKPFILE_CONFIG", originalEnv)
+		} else {
+			os.Unsetenv("BKPFILE_CONFIG")
+		}
+	}()
+
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-provider-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	os.Setenv("BKPFILE_CONFIG", "global.yml")
+	if err := os.WriteFile("global.yml", []byte(`backup_dir_path: "./first-backup"`), 0644); err != nil {
+		t.Fatalf("Failed to create global config: %v", err)
+	}
+
+	provider, _, err := NewConfigProvider(afero.NewOsFs(), tmpDir)
+	if err != nil {
+		t.Fatalf("NewConfigProvider() error = %v", err)
+	}
+
+	firstCfg := provider.Current()
+	if firstCfg.BackupDirPath != "./first-backup" {
+		t.Fatalf("Current().BackupDirPath = %q, want %q", firstCfg.BackupDirPath, "./first-backup")
+	}
+
+	if err := os.WriteFile("global.yml", []byte(`backup_dir_path: "./second-backup"`), 0644); err != nil {
+		t.Fatalf("Failed to rewrite global config: %v", err)
+	}
+
+	if _, err := provider.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+
+	secondCfg := provider.Current()
+	if secondCfg.BackupDirPath != "./second-backup" {
+		t.Errorf("Current().BackupDirPath after reload = %q, want %q", secondCfg.BackupDirPath, "./second-backup")
+	}
+	if firstCfg.BackupDirPath != "./first-backup" {
+		t.Errorf("snapshot taken before Reload changed to %q, want it to stay %q", firstCfg.BackupDirPath, "./first-backup")
+	}
+	if firstCfg == secondCfg {
+		t.Errorf("Current() returned the same *Config pointer before and after Reload")
+	}
+}
+
+// TestConfigProviderReloadKeepsPreviousOnError asserts a Reload that hits an
+// invalid config leaves Current() returning the last good snapshot rather
+// than a half-built or nil one.
+func TestConfigProviderReloadKeepsPreviousOnError(t *testing.T) {
+	originalEnv := os.Getenv("BKPFILE_CONFIG")
+	defer func() {
+		if originalEnv != "" {
+			os.Setenv("BKPFILE_CONFIG", originalEnv)
+		} else {
+			os.Unsetenv("BKPFILE_CONFIG")
+		}
+	}()
+
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-provider-error-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, ".bkpfile.yml")
+	if err := os.WriteFile(configPath, []byte(`retention:
+  keep_last: 3
+`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	provider, _, err := NewConfigProvider(afero.NewOsFs(), tmpDir)
+	if err != nil {
+		t.Fatalf("NewConfigProvider() error = %v", err)
+	}
+	goodCfg := provider.Current()
+
+	if err := os.WriteFile(configPath, []byte(`retention:
+  keep_last: -1
+`), 0644); err != nil {
+		t.Fatalf("Failed to rewrite config file: %v", err)
+	}
+
+	if _, err := provider.Reload(); err == nil {
+		t.Fatalf("Reload() with an invalid retention policy returned nil error")
+	}
+
+	if provider.Current() != goodCfg {
+		t.Errorf("Current() after a failed Reload should still return the last good snapshot")
+	}
+}
@@ -211,7 +211,7 @@ use_current_dir_name: false`,
 			}
 
 			// Load configuration
-			cfg, err := LoadConfig(tmpDir)
+			cfg, _, err := LoadConfig(tmpDir)
 
 			if tt.wantErr {
 				if err == nil {
@@ -306,7 +306,7 @@ use_current_dir_name: false`,
 			os.Setenv("BKPFILE_CONFIG", tt.envValue)
 
 			// Load configuration
-			cfg, err := LoadConfig(tmpDir)
+			cfg, _, err := LoadConfig(tmpDir)
 			if err != nil {
 				t.Errorf("LoadConfig() unexpected error: %v", err)
 				return
@@ -466,7 +466,7 @@ config: "custom.yml"`,
 			os.Stdout = w
 
 			// Run DisplayConfig
-			err := DisplayConfig()
+			_, err := DisplayConfig()
 
 			// Restore stdout
 			w.Close()
@@ -0,0 +1,199 @@
+package bkpfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// configBackupConfigName is the normalized merged config written by
+// ConfigBackup alongside the captured sources.
+const configBackupConfigName = "config.yaml"
+
+// configBackupSourcesDir is the directory under a config backup holding a
+// copy of each file discovered via GetConfigSearchPath().
+const configBackupSourcesDir = "sources"
+
+// configBackupManifestName records provenance for each file captured under
+// configBackupSourcesDir.
+const configBackupManifestName = "manifest.json"
+
+// ConfigBackupSource is a single entry in a ConfigBackupManifest.
+// Architecture: Data Objects - ConfigBackupSource
+type ConfigBackupSource struct {
+	// Path is the original absolute path the source file was read from.
+	Path string `json:"path"`
+	// SHA256 is the hex-encoded SHA-256 of the source file's contents.
+	SHA256 string `json:"sha256"`
+	// Mode is the source file's os.FileMode at backup time.
+	Mode os.FileMode `json:"mode"`
+	// ModTime is the source file's modification time at backup time.
+	ModTime time.Time `json:"mod_time"`
+}
+
+// ConfigBackupManifest records, for each config source file captured in a
+// config backup, where it came from, its hash, and enough of its
+// os.FileInfo to restore it faithfully.
+// Architecture: Data Objects - ConfigBackupManifest
+type ConfigBackupManifest struct {
+	Sources []ConfigBackupSource `json:"sources"`
+}
+
+// ConfigBackup snapshots bkpfile's effective configuration into dirPath:
+// a normalized config.yaml holding the merged Config, a sources/
+// directory holding a copy of every file discovered via
+// GetConfigSearchPath(), and a manifest.json recording each source's
+// path, SHA-256, and file mode/mtime. dirPath's parent must already
+// exist; dirPath itself must not.
+// Architecture: Core Functions - Configuration Management - ConfigBackup
+func ConfigBackup(dirPath string) error {
+	cfg, _, err := LoadConfig(".")
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	if err := os.Mkdir(dirPath, 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+
+	configData, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dirPath, configBackupConfigName), configData, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", configBackupConfigName, err)
+	}
+
+	sourcesDir := filepath.Join(dirPath, configBackupSourcesDir)
+	if err := os.Mkdir(sourcesDir, 0755); err != nil {
+		return fmt.Errorf("failed to create sources directory: %w", err)
+	}
+
+	var manifest ConfigBackupManifest
+	for _, path := range GetConfigSearchPath() {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", path, err)
+		}
+
+		info, err := os.Stat(absPath)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to stat %s: %w", absPath, err)
+		}
+
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", absPath, err)
+		}
+
+		destPath := filepath.Join(sourcesDir, filepath.Base(absPath))
+		if err := os.WriteFile(destPath, data, info.Mode()); err != nil {
+			return fmt.Errorf("failed to write %s: %w", destPath, err)
+		}
+
+		sum := sha256.Sum256(data)
+		manifest.Sources = append(manifest.Sources, ConfigBackupSource{
+			Path:    absPath,
+			SHA256:  hex.EncodeToString(sum[:]),
+			Mode:    info.Mode(),
+			ModTime: info.ModTime(),
+		})
+	}
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dirPath, configBackupManifestName), manifestData, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", configBackupManifestName, err)
+	}
+
+	return nil
+}
+
+// loadConfigBackupManifest reads and parses the manifest.json written by
+// ConfigBackup at dirPath.
+func loadConfigBackupManifest(dirPath string) (ConfigBackupManifest, error) {
+	manifestData, err := os.ReadFile(filepath.Join(dirPath, configBackupManifestName))
+	if err != nil {
+		return ConfigBackupManifest{}, fmt.Errorf("failed to read %s: %w", configBackupManifestName, err)
+	}
+
+	var manifest ConfigBackupManifest
+	if err := json.Unmarshal(manifestData, &manifest); err != nil {
+		return ConfigBackupManifest{}, fmt.Errorf("%s is not valid JSON: %w", configBackupManifestName, err)
+	}
+	return manifest, nil
+}
+
+// restoreConfigBackupSources copies every source recorded in dirPath's
+// manifest.json back to its original path, rooted under targetRoot,
+// restoring its recorded mode and modification time. overwrite controls
+// whether an existing file at the destination is replaced.
+func restoreConfigBackupSources(dirPath string, targetRoot string, overwrite bool) error {
+	manifest, err := loadConfigBackupManifest(dirPath)
+	if err != nil {
+		return err
+	}
+
+	sourcesDir := filepath.Join(dirPath, configBackupSourcesDir)
+	for _, source := range manifest.Sources {
+		srcPath := filepath.Join(sourcesDir, filepath.Base(source.Path))
+		data, err := os.ReadFile(srcPath)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", srcPath, err)
+		}
+
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != source.SHA256 {
+			return fmt.Errorf("%s does not match the checksum recorded in %s", srcPath, configBackupManifestName)
+		}
+
+		destPath := filepath.Join(targetRoot, source.Path)
+
+		if !overwrite {
+			if _, err := os.Stat(destPath); err == nil {
+				return &ConfigFileAlreadyExistsError{Path: destPath}
+			} else if !os.IsNotExist(err) {
+				return fmt.Errorf("failed to check for existing file %s: %w", destPath, err)
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", destPath, err)
+		}
+		if err := os.WriteFile(destPath, data, source.Mode); err != nil {
+			return fmt.Errorf("failed to write %s: %w", destPath, err)
+		}
+		if err := os.Chtimes(destPath, source.ModTime, source.ModTime); err != nil {
+			return fmt.Errorf("failed to set modification time on %s: %w", destPath, err)
+		}
+	}
+
+	return nil
+}
+
+// ConfigRestore rewrites every source file recorded in the manifest.json
+// under dirPath (as written by ConfigBackup) back into targetRoot,
+// overwriting any file already present at the destination. Use
+// SafeConfigRestore to refuse instead.
+// Architecture: Core Functions - Configuration Management - ConfigRestore
+func ConfigRestore(dirPath string, targetRoot string) error {
+	return restoreConfigBackupSources(dirPath, targetRoot, true)
+}
+
+// SafeConfigRestore is like ConfigRestore but returns a
+// *ConfigFileAlreadyExistsError instead of overwriting a file that
+// already exists at its destination.
+// Architecture: Core Functions - Configuration Management - SafeConfigRestore
+func SafeConfigRestore(dirPath string, targetRoot string) error {
+	return restoreConfigBackupSources(dirPath, targetRoot, false)
+}
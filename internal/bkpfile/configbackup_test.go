@@ -0,0 +1,89 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigBackupAndRestoreRoundTrip(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-backup-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceDir := filepath.Join(tmpDir, "source")
+	if err := os.Mkdir(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create source dir: %v", err)
+	}
+	configPath := filepath.Join(sourceDir, ".bkpfile.yml")
+	if err := os.WriteFile(configPath, []byte("backup_dir_path: /custom-backups\n"), 0644); err != nil {
+		t.Fatalf("Failed to seed config file: %v", err)
+	}
+
+	os.Setenv("BKPFILE_CONFIG", configPath)
+	defer os.Unsetenv("BKPFILE_CONFIG")
+
+	backupDir := filepath.Join(tmpDir, "backup")
+	if err := ConfigBackup(backupDir); err != nil {
+		t.Fatalf("ConfigBackup() error = %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(backupDir, configBackupConfigName)); err != nil {
+		t.Errorf("expected %s to exist: %v", configBackupConfigName, err)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, configBackupManifestName)); err != nil {
+		t.Errorf("expected %s to exist: %v", configBackupManifestName, err)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, configBackupSourcesDir, ".bkpfile.yml")); err != nil {
+		t.Errorf("expected captured source to exist: %v", err)
+	}
+
+	if err := os.Remove(configPath); err != nil {
+		t.Fatalf("Failed to remove original config file: %v", err)
+	}
+
+	restoreRoot := filepath.Join(tmpDir, "restored")
+	if err := ConfigRestore(backupDir, restoreRoot); err != nil {
+		t.Fatalf("ConfigRestore() error = %v", err)
+	}
+
+	restoredData, err := os.ReadFile(filepath.Join(restoreRoot, configPath))
+	if err != nil {
+		t.Fatalf("Failed to read restored config file: %v", err)
+	}
+	if string(restoredData) != "backup_dir_path: /custom-backups\n" {
+		t.Errorf("restored config file = %q, want original contents", restoredData)
+	}
+}
+
+func TestSafeConfigRestoreRefusesExisting(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-backup-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceDir := filepath.Join(tmpDir, "source")
+	if err := os.Mkdir(sourceDir, 0755); err != nil {
+		t.Fatalf("Failed to create source dir: %v", err)
+	}
+	configPath := filepath.Join(sourceDir, ".bkpfile.yml")
+	if err := os.WriteFile(configPath, []byte("backup_dir_path: /custom-backups\n"), 0644); err != nil {
+		t.Fatalf("Failed to seed config file: %v", err)
+	}
+
+	os.Setenv("BKPFILE_CONFIG", configPath)
+	defer os.Unsetenv("BKPFILE_CONFIG")
+
+	backupDir := filepath.Join(tmpDir, "backup")
+	if err := ConfigBackup(backupDir); err != nil {
+		t.Fatalf("ConfigBackup() error = %v", err)
+	}
+
+	err = SafeConfigRestore(backupDir, "/")
+	if _, ok := err.(*ConfigFileAlreadyExistsError); !ok {
+		t.Fatalf("SafeConfigRestore() error = %v, want *ConfigFileAlreadyExistsError", err)
+	}
+}
@@ -0,0 +1,99 @@
+package bkpfile
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+)
+
+// envVarPattern matches the same two environment variable reference forms
+// os.ExpandEnv recognizes, "${VAR}" and bare "$VAR", so config authors can
+// use whichever style their shell config already uses.
+var envVarPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}|\$([A-Za-z_][A-Za-z0-9_]*)`)
+
+// expandConfigString expands ${VAR}/$VAR references and a leading ~ in s.
+// With strict=false, a reference to an unset variable expands to "", the
+// same as os.ExpandEnv; with strict=true (config_strict_env: true) it is
+// reported as an error instead of silently producing an empty value.
+func expandConfigString(s string, strict bool) (string, error) {
+	var firstErr error
+	expanded := envVarPattern.ReplaceAllStringFunc(s, func(match string) string {
+		sub := envVarPattern.FindStringSubmatch(match)
+		name := sub[1]
+		if name == "" {
+			name = sub[2]
+		}
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			if firstErr == nil && strict {
+				firstErr = fmt.Errorf("undefined environment variable %q", name)
+			}
+			return ""
+		}
+		return value
+	})
+	if firstErr != nil {
+		return "", firstErr
+	}
+	return expandHomePath(expanded)
+}
+
+// expandEnvInValue recursively expands string scalars within v (a value
+// decoded from YAML into map[string]interface{}/[]interface{}/scalars),
+// leaving non-string scalars untouched. The "expr" key is skipped: its
+// value is expr source code evaluated against its own environment, not a
+// path or URL meant for shell-style expansion.
+func expandEnvInValue(key string, v interface{}, strict bool) (interface{}, error) {
+	switch val := v.(type) {
+	case string:
+		return expandConfigString(val, strict)
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(val))
+		for k, child := range val {
+			if k == "expr" {
+				out[k] = child
+				continue
+			}
+			expanded, err := expandEnvInValue(k, child, strict)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = expanded
+		}
+		return out, nil
+	case []interface{}:
+		out := make([]interface{}, len(val))
+		for i, child := range val {
+			expanded, err := expandEnvInValue(key, child, strict)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = expanded
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// expandEnvInConfig walks yamlData's string scalars and expands
+// ${VAR}/$VAR/~ references before it is re-marshalled and unmarshalled
+// into a Config, honoring that same file's own config_strict_env setting.
+// It returns a new map; yamlData itself is left unmodified.
+func expandEnvInConfig(yamlData map[string]interface{}) (map[string]interface{}, error) {
+	strict, _ := yamlData["config_strict_env"].(bool)
+
+	out := make(map[string]interface{}, len(yamlData))
+	for key, value := range yamlData {
+		if key == "config_strict_env" {
+			out[key] = value
+			continue
+		}
+		expanded, err := expandEnvInValue(key, value, strict)
+		if err != nil {
+			return nil, err
+		}
+		out[key] = expanded
+	}
+	return out, nil
+}
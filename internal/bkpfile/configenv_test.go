@@ -0,0 +1,141 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestConfigEnvExpansion is parallel to TestConfigurationIntegration: it
+// sets BKPFILE_BACKUP_ROOT and writes "${BKPFILE_BACKUP_ROOT}/..." into
+// global.yml/local.yml, and checks the env reference is expanded before
+// global.yml:local.yml precedence is applied.
+func TestConfigEnvExpansion(t *testing.T) {
+	originalEnv := os.Getenv("BKPFILE_CONFIG")
+	originalRoot := os.Getenv("BKPFILE_BACKUP_ROOT")
+	defer func() {
+		if originalEnv != "" {
+			os.Setenv("BKPFILE_CONFIG", originalEnv)
+		} else {
+			os.Unsetenv("BKPFILE_CONFIG")
+		}
+		if originalRoot != "" {
+			os.Setenv("BKPFILE_BACKUP_ROOT", originalRoot)
+		} else {
+			os.Unsetenv("BKPFILE_BACKUP_ROOT")
+		}
+	}()
+
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-env-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	os.Setenv("BKPFILE_BACKUP_ROOT", "/tmp/x")
+
+	globalConfig := `backup_dir_path: "${BKPFILE_BACKUP_ROOT}/global"`
+	localConfig := `backup_dir_path: "${BKPFILE_BACKUP_ROOT}/local"`
+	if err := os.WriteFile("global.yml", []byte(globalConfig), 0644); err != nil {
+		t.Fatalf("Failed to create global config: %v", err)
+	}
+	if err := os.WriteFile("local.yml", []byte(localConfig), 0644); err != nil {
+		t.Fatalf("Failed to create local config: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		envValue string
+		want     string
+	}{
+		{name: "global config expands env var", envValue: "global.yml", want: filepath.ToSlash("/tmp/x/global")},
+		{name: "local config expands env var", envValue: "local.yml", want: filepath.ToSlash("/tmp/x/local")},
+		{name: "precedence applies to expanded values", envValue: "global.yml:local.yml", want: filepath.ToSlash("/tmp/x/global")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv("BKPFILE_CONFIG", tt.envValue)
+
+			cfg, _, err := LoadConfig(".")
+			if err != nil {
+				t.Fatalf("LoadConfig() unexpected error: %v", err)
+			}
+
+			if got := filepath.ToSlash(cfg.BackupDirPath); got != tt.want {
+				t.Errorf("LoadConfig().BackupDirPath = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestConfigEnvExpansionUnsetVariable covers the config_strict_env switch:
+// an undefined variable expands to "" by default, but errors when the
+// config file opts into config_strict_env: true.
+func TestConfigEnvExpansionUnsetVariable(t *testing.T) {
+	originalEnv := os.Getenv("BKPFILE_CONFIG")
+	defer func() {
+		if originalEnv != "" {
+			os.Setenv("BKPFILE_CONFIG", originalEnv)
+		} else {
+			os.Unsetenv("BKPFILE_CONFIG")
+		}
+	}()
+	os.Unsetenv("BKPFILE_DOES_NOT_EXIST")
+
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-env-strict-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name       string
+		yamlConfig string
+		wantErr    bool
+		want       string
+	}{
+		{
+			name:       "lenient mode expands unset variable to empty",
+			yamlConfig: `backup_dir_path: "${BKPFILE_DOES_NOT_EXIST}/backups"`,
+			wantErr:    false,
+			want:       "/backups",
+		},
+		{
+			name: "strict mode errors on unset variable",
+			yamlConfig: `config_strict_env: true
+backup_dir_path: "${BKPFILE_DOES_NOT_EXIST}/backups"`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(tmpDir, ".bkpfile.yml")
+			if err := os.WriteFile(configPath, []byte(tt.yamlConfig), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			defer os.Remove(configPath)
+
+			cfg, _, err := LoadConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if cfg.BackupDirPath != tt.want {
+				t.Errorf("LoadConfig().BackupDirPath = %q, want %q", cfg.BackupDirPath, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package bkpfile
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// exprableConfigKeys lists the top-level config keys that may be written
+// as an `expr: "..."` expression instead of a literal value.
+var exprableConfigKeys = map[string]bool{
+	"backup_dir_path": true,
+}
+
+// ConfigContext is the expression environment exposed to a config file's
+// `expr:` values, letting a value depend on where and when bkpfile is
+// running instead of being a fixed literal.
+// Architecture: Data Objects - ConfigContext
+type ConfigContext struct {
+	// Env holds the process's environment variables, keyed by name.
+	Env map[string]string
+	// Cwd is the absolute current working directory.
+	Cwd string
+	// CwdBase is the base name (last path element) of Cwd.
+	CwdBase string
+	// Hostname is the machine's hostname, or "" if it can't be determined.
+	Hostname string
+	// User is the current user's username, or "" if it can't be determined.
+	User string
+	// Now is the time the context was built.
+	Now time.Time
+}
+
+// NewConfigContext builds the ConfigContext that `expr:` config values
+// are evaluated against: the process environment, current directory,
+// hostname, user, and the current time.
+// Architecture: Core Functions - Configuration Management - NewConfigContext
+func NewConfigContext() ConfigContext {
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if i := strings.IndexByte(kv, '='); i >= 0 {
+			env[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	cwd, _ := os.Getwd()
+	hostname, _ := os.Hostname()
+
+	userName := ""
+	if u, err := user.Current(); err == nil {
+		userName = u.Username
+	}
+
+	return ConfigContext{
+		Env:      env,
+		Cwd:      cwd,
+		CwdBase:  filepath.Base(cwd),
+		Hostname: hostname,
+		User:     userName,
+		Now:      time.Now(),
+	}
+}
+
+// exprEnv converts a ConfigContext into the variable names an `expr:`
+// expression sees: env, cwd, cwd_base, hostname, user, and now.
+func (c ConfigContext) exprEnv() map[string]interface{} {
+	return map[string]interface{}{
+		"env":      c.Env,
+		"cwd":      c.Cwd,
+		"cwd_base": c.CwdBase,
+		"hostname": c.Hostname,
+		"user":     c.User,
+		"now":      c.Now,
+	}
+}
+
+// configExpr is a single `expr:` value found while loading a config
+// file: which top-level field it targets, its source text, and its
+// compiled program.
+type configExpr struct {
+	field   string
+	source  string
+	program *vm.Program
+}
+
+// extractConfigExprs scans yamlData for top-level keys in
+// exprableConfigKeys whose value is a map with an "expr" key, compiles
+// each one, and returns a copy of yamlData with those keys removed (so
+// the remainder can be unmarshaled into Config as usual) alongside the
+// compiled expressions.
+func extractConfigExprs(path string, yamlData map[string]interface{}) (map[string]interface{}, []configExpr, error) {
+	env := ConfigContext{}.exprEnv()
+
+	cleaned := make(map[string]interface{}, len(yamlData))
+	var exprs []configExpr
+
+	for key, value := range yamlData {
+		if !exprableConfigKeys[key] {
+			cleaned[key] = value
+			continue
+		}
+
+		asMap, ok := value.(map[string]interface{})
+		if !ok {
+			cleaned[key] = value
+			continue
+		}
+
+		source, ok := asMap["expr"].(string)
+		if !ok {
+			return nil, nil, fmt.Errorf("%s: %q must be a scalar value or a map with an \"expr\" key", path, key)
+		}
+
+		program, err := expr.Compile(source, expr.Env(env))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: invalid expr for %q: %w", path, key, err)
+		}
+
+		exprs = append(exprs, configExpr{field: key, source: source, program: program})
+	}
+
+	return cleaned, exprs, nil
+}
+
+// ResolvedExpr is the evaluated result of a single `expr:` config value.
+// Architecture: Data Objects - ResolvedExpr
+type ResolvedExpr struct {
+	// Source is the raw expression text as written in the config file.
+	Source string
+	// Value is the expression's evaluated result, formatted as a string.
+	Value string
+}
+
+// ResolvedConfig is a Config with every `expr:` value evaluated to a
+// concrete literal against a ConfigContext.
+// Architecture: Data Objects - ResolvedConfig
+type ResolvedConfig struct {
+	Config
+
+	// Expressions records, by config field name, the source text and
+	// evaluated value of every field that was written as an `expr:`
+	// value.
+	Expressions map[string]ResolvedExpr
+}
+
+// Resolve evaluates every `expr:` value found while loading cfg against
+// ctx and returns a copy of cfg with those fields replaced by the
+// results.
+// Architecture: Core Functions - Configuration Management - Resolve
+func (cfg *Config) Resolve(ctx ConfigContext) (*ResolvedConfig, error) {
+	resolved := &ResolvedConfig{
+		Config:      *cfg,
+		Expressions: make(map[string]ResolvedExpr, len(cfg.exprs)),
+	}
+
+	env := ctx.exprEnv()
+	for _, ce := range cfg.exprs {
+		output, err := expr.Run(ce.program, env)
+		if err != nil {
+			return nil, fmt.Errorf("failed to evaluate expr for %q: %w", ce.field, err)
+		}
+		value := fmt.Sprintf("%v", output)
+
+		resolved.Expressions[ce.field] = ResolvedExpr{Source: ce.source, Value: value}
+
+		switch ce.field {
+		case "backup_dir_path":
+			resolved.BackupDirPath = value
+		}
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,56 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigEvaluatesExprBackupDirPath(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-expr-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configPath := filepath.Join(tmpDir, ".bkpfile.yml")
+	yamlContent := "backup_dir_path:\n  expr: \"cwd_base + '/backups'\"\n"
+	if err := os.WriteFile(configPath, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, diags, err := LoadConfig(tmpDir)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if diags.HasError() {
+		t.Fatalf("LoadConfig() diagnostics = %v", diags)
+	}
+
+	resolved, err := cfg.Resolve(NewConfigContext())
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+
+	want := filepath.Base(mustGetwd(t)) + "/backups"
+	if resolved.BackupDirPath != want {
+		t.Errorf("Resolve().BackupDirPath = %q, want %q", resolved.BackupDirPath, want)
+	}
+
+	re, ok := resolved.Expressions["backup_dir_path"]
+	if !ok {
+		t.Fatalf("Resolve().Expressions missing \"backup_dir_path\"")
+	}
+	if re.Source != "cwd_base + '/backups'" {
+		t.Errorf("Expressions[\"backup_dir_path\"].Source = %q, want %q", re.Source, "cwd_base + '/backups'")
+	}
+}
+
+func mustGetwd(t *testing.T) string {
+	t.Helper()
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("os.Getwd() error = %v", err)
+	}
+	return cwd
+}
@@ -0,0 +1,60 @@
+package bkpfile
+
+import "sync"
+
+// InMemoryConfigPath is a GetConfigPath()/BKPFILE_CONFIG search path
+// sentinel: a path of InMemoryConfigPath+name tells LoadConfig to read
+// the buffer registered under name by SetInMemoryConfig instead of a
+// file on disk, so a program embedding bkpfile doesn't have to own a
+// config file on disk just to configure it.
+const InMemoryConfigPath = ":memory:"
+
+var (
+	configPathMu       sync.RWMutex
+	configPathOverride string
+
+	inMemoryConfigsMu sync.RWMutex
+	inMemoryConfigs   = map[string][]byte{}
+)
+
+// GetConfigPath returns the search path last set by SetConfigPath, or ""
+// if none has been set. LoadConfig consults this before falling back to
+// the BKPFILE_CONFIG environment variable.
+// Architecture: Core Functions - Configuration Management - GetConfigPath
+func GetConfigPath() string {
+	configPathMu.RLock()
+	defer configPathMu.RUnlock()
+	return configPathOverride
+}
+
+// SetConfigPath overrides the config search path LoadConfig uses, taking
+// precedence over BKPFILE_CONFIG without having to mutate the process
+// environment. Pass InMemoryConfigPath+name to read a buffer registered
+// with SetInMemoryConfig; pass "" to clear the override and revert to
+// BKPFILE_CONFIG.
+// Architecture: Core Functions - Configuration Management - SetConfigPath
+func SetConfigPath(paths string) error {
+	configPathMu.Lock()
+	defer configPathMu.Unlock()
+	configPathOverride = paths
+	return nil
+}
+
+// SetInMemoryConfig registers data under name so that a search path entry
+// of InMemoryConfigPath+name reads it instead of a file on disk.
+// Architecture: Core Functions - Configuration Management - SetInMemoryConfig
+func SetInMemoryConfig(name string, data []byte) {
+	inMemoryConfigsMu.Lock()
+	defer inMemoryConfigsMu.Unlock()
+	inMemoryConfigs[name] = data
+}
+
+// GetInMemoryConfig returns the buffer registered under name by
+// SetInMemoryConfig, and whether one was found.
+// Architecture: Core Functions - Configuration Management - GetInMemoryConfig
+func GetInMemoryConfig(name string) ([]byte, bool) {
+	inMemoryConfigsMu.RLock()
+	defer inMemoryConfigsMu.RUnlock()
+	data, ok := inMemoryConfigs[name]
+	return data, ok
+}
@@ -0,0 +1,68 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestConfigWriteConfig(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-write-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := DefaultConfig()
+	cfg.BackupDirPath = "/tmp/custom-backups"
+
+	destPath := filepath.Join(tmpDir, "nested", ".bkpfile.yml")
+	if err := cfg.WriteConfig(destPath); err != nil {
+		t.Fatalf("WriteConfig() error = %v", err)
+	}
+
+	loaded, _, err := LoadConfig(filepath.Dir(destPath))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if loaded.BackupDirPath != cfg.BackupDirPath {
+		t.Errorf("BackupDirPath = %q, want %q", loaded.BackupDirPath, cfg.BackupDirPath)
+	}
+}
+
+func TestConfigSafeWriteConfigRefusesExisting(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-config-write-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	destPath := filepath.Join(tmpDir, ".bkpfile.yml")
+	if err := os.WriteFile(destPath, []byte("backup_dir_path: /existing\n"), 0644); err != nil {
+		t.Fatalf("Failed to seed existing config file: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	err = cfg.SafeWriteConfig(destPath)
+	if _, ok := err.(*ConfigFileAlreadyExistsError); !ok {
+		t.Fatalf("SafeWriteConfig() error = %v, want *ConfigFileAlreadyExistsError", err)
+	}
+
+	data, readErr := os.ReadFile(destPath)
+	if readErr != nil {
+		t.Fatalf("Failed to read existing config file: %v", readErr)
+	}
+	if string(data) != "backup_dir_path: /existing\n" {
+		t.Errorf("existing config file was modified: %q", data)
+	}
+}
+
+func TestResolveConfigPathExplicitPathBypassesSearchPath(t *testing.T) {
+	path, err := ResolveConfigPath("/explicit/path/.bkpfile.yml")
+	if err != nil {
+		t.Fatalf("ResolveConfigPath() with explicit path returned error: %v", err)
+	}
+	if path != "/explicit/path/.bkpfile.yml" {
+		t.Errorf("ResolveConfigPath() = %q, want explicit path preserved", path)
+	}
+}
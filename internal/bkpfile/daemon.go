@@ -0,0 +1,229 @@
+package bkpfile
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Profile represents a named recurring backup schedule run by the daemon
+// Architecture: Data Objects - Profile
+type Profile struct {
+	// Name identifies the profile for logging and diffing on reload
+	// Architecture: Profile.Name
+	Name string `yaml:"name"`
+
+	// Paths lists the files this profile backs up on each scheduled run
+	// Architecture: Profile.Paths
+	Paths []string `yaml:"paths"`
+
+	// Note is attached to every backup created by this profile
+	// Architecture: Profile.Note
+	Note string `yaml:"note"`
+
+	// Schedule is a standard cron expression (seconds field optional)
+	// Architecture: Profile.Schedule
+	Schedule string `yaml:"schedule"`
+
+	// BackupDirPath overrides the top-level backup directory for this profile
+	// Architecture: Profile.BackupDirPath
+	BackupDirPath string `yaml:"backup_dir_path"`
+}
+
+// cronParser accepts the same cron expressions the daemon schedules with:
+// the standard five fields, plus an optional leading seconds field (so
+// both "*/5 * * * *" and "*/30 * * * * *" are valid).
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// validateSchedule reports whether spec parses as a cron expression
+// cronParser (and therefore the daemon's scheduler) accepts.
+func validateSchedule(spec string) error {
+	_, err := cronParser.Parse(spec)
+	return err
+}
+
+// DaemonOptions controls optional behavior of a Daemon.
+type DaemonOptions struct {
+	// DryRun, when true, makes every scheduled run call CreateBackupWithTime
+	// with dryRun=true, so a profile's cron expression and paths can be
+	// validated without writing any backups.
+	DryRun bool
+}
+
+// Daemon runs bkpfile as a long-lived process, scheduling one cron entry per
+// configured profile and reloading the schedule set on SIGHUP.
+// Architecture: Data Objects - Daemon
+type Daemon struct {
+	root      string
+	provider  *ConfigProvider
+	opts      DaemonOptions
+	cron      *cron.Cron
+	formatter *OutputFormatter
+	signals   chan os.Signal
+	done      chan struct{}
+
+	mu      sync.Mutex
+	entries map[string]cron.EntryID
+}
+
+// NewDaemon creates a Daemon that schedules the profiles in cfg, resolving
+// relative backup paths against root. cfg is wrapped in a ConfigProvider so
+// SIGHUP can swap in a freshly-reloaded snapshot without disturbing any
+// backup already running against the previous one.
+// Architecture: Core Functions - Daemon Management - NewDaemon
+func NewDaemon(root string, cfg *Config, opts DaemonOptions) *Daemon {
+	return &Daemon{
+		root:      root,
+		provider:  NewConfigProviderFromConfig(cfg.fs(), root, cfg),
+		opts:      opts,
+		cron:      cron.New(cron.WithParser(cronParser)),
+		formatter: NewOutputFormatter(cfg),
+		signals:   make(chan os.Signal, 1),
+		done:      make(chan struct{}),
+		entries:   make(map[string]cron.EntryID),
+	}
+}
+
+// Run starts the cron scheduler, registers an entry per profile, and blocks
+// until Stop is called or the process receives SIGINT/SIGTERM (graceful
+// shutdown) or SIGHUP (config reload, not a shutdown).
+// Architecture: Core Functions - Daemon Management - Run
+func (d *Daemon) Run() error {
+	signal.Notify(d.signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(d.signals)
+
+	d.mu.Lock()
+	d.scheduleProfiles(d.provider.Current().Profiles)
+	d.mu.Unlock()
+
+	d.cron.Start()
+	defer func() { <-d.cron.Stop().Done() }()
+
+	for {
+		select {
+		case sig := <-d.signals:
+			if sig == syscall.SIGHUP {
+				if err := d.reloadConfig(); err != nil {
+					d.formatter.PrintError(fmt.Sprintf("failed to reload config: %v", err))
+				}
+				continue
+			}
+			// SIGINT/SIGTERM: stop accepting new runs and let any
+			// in-flight run finish via the deferred d.cron.Stop() above.
+			return nil
+		case <-d.done:
+			return nil
+		}
+	}
+}
+
+// Stop signals Run to return, allowing in-flight cron jobs to finish.
+// Architecture: Core Functions - Daemon Management - Stop
+func (d *Daemon) Stop() {
+	close(d.done)
+}
+
+// scheduleProfiles registers one cron.EntryID per profile. Callers must hold d.mu.
+func (d *Daemon) scheduleProfiles(profiles []Profile) {
+	for _, profile := range profiles {
+		profile := profile
+		id, err := d.cron.AddFunc(profile.Schedule, func() { d.runProfile(profile) })
+		if err != nil {
+			d.formatter.PrintError(fmt.Sprintf("failed to schedule profile %q: %v", profile.Name, err))
+			continue
+		}
+		d.entries[profile.Name] = id
+	}
+}
+
+// reloadConfig re-runs LoadConfig, diffs the old and new profile sets, and
+// adds/removes cron entries in place without dropping in-flight backups.
+// Architecture: Core Functions - Daemon Management - reloadConfig
+func (d *Daemon) reloadConfig() error {
+	oldCfg := d.provider.Current()
+
+	diags, err := d.provider.Reload()
+	if err != nil {
+		return err
+	}
+	for _, w := range diags.Warnings() {
+		d.formatter.PrintError(fmt.Sprintf("config warning (%s): %s", w.Path, w.Summary))
+	}
+	newCfg := d.provider.Current()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	newProfiles := make(map[string]Profile, len(newCfg.Profiles))
+	for _, p := range newCfg.Profiles {
+		newProfiles[p.Name] = p
+	}
+
+	// Remove entries for profiles that disappeared or changed schedule.
+	for name, id := range d.entries {
+		newProfile, stillExists := newProfiles[name]
+		if !stillExists || newProfile.Schedule != schedulesByName(oldCfg.Profiles)[name] {
+			d.cron.Remove(id)
+			delete(d.entries, name)
+		}
+	}
+
+	// Add entries for profiles that are new or whose schedule changed.
+	var toAdd []Profile
+	for name, p := range newProfiles {
+		if _, stillScheduled := d.entries[name]; !stillScheduled {
+			toAdd = append(toAdd, p)
+		}
+	}
+	d.scheduleProfiles(toAdd)
+
+	d.formatter = NewOutputFormatter(newCfg)
+	return nil
+}
+
+// schedulesByName indexes a profile slice by name for schedule-change comparisons.
+func schedulesByName(profiles []Profile) map[string]string {
+	out := make(map[string]string, len(profiles))
+	for _, p := range profiles {
+		out[p.Name] = p.Schedule
+	}
+	return out
+}
+
+// runProfile executes one scheduled run of a profile, backing up each of its
+// paths and logging the outcome through the daemon's OutputFormatter.
+// Architecture: Core Functions - Daemon Management - runProfile
+func (d *Daemon) runProfile(profile Profile) {
+	cfg := *d.provider.Current()
+	if profile.BackupDirPath != "" {
+		cfg.BackupDirPath = profile.BackupDirPath
+	}
+
+	for _, path := range profile.Paths {
+		err := CreateBackupWithTime(&cfg, path, profile.Note, d.opts.DryRun, timeNow)
+		if err == nil {
+			continue
+		}
+		backupErr, ok := err.(*BackupError)
+		if !ok {
+			d.formatter.PrintError(fmt.Sprintf("profile %q: %v", profile.Name, err))
+			continue
+		}
+		switch backupErr.StatusCode {
+		case cfg.StatusCreatedBackup:
+			if d.opts.DryRun {
+				d.formatter.PrintError(fmt.Sprintf("profile %q: would create backup for %s", profile.Name, path))
+			} else {
+				d.formatter.PrintError(fmt.Sprintf("profile %q: created backup for %s", profile.Name, path))
+			}
+		case cfg.StatusFileIsIdenticalToExistingBackup:
+			d.formatter.PrintError(fmt.Sprintf("profile %q: %s is identical to its most recent backup, skipped", profile.Name, path))
+		default:
+			d.formatter.PrintError(fmt.Sprintf("profile %q: %v", profile.Name, err))
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigProfiles(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-daemon-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name       string
+		yamlConfig string
+		wantErr    bool
+		wantCount  int
+	}{
+		{
+			name: "valid five-field schedule",
+			yamlConfig: `profiles:
+  - name: hourly-notes
+    paths:
+      - notes.txt
+    schedule: "0 * * * *"
+`,
+			wantErr:   false,
+			wantCount: 1,
+		},
+		{
+			name: "valid schedule with seconds field",
+			yamlConfig: `profiles:
+  - name: frequent-notes
+    paths:
+      - notes.txt
+    schedule: "*/30 * * * * *"
+`,
+			wantErr:   false,
+			wantCount: 1,
+		},
+		{
+			name: "invalid schedule",
+			yamlConfig: `profiles:
+  - name: broken
+    paths:
+      - notes.txt
+    schedule: "not a cron expression"
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(tmpDir, ".bkpfile.yml")
+			if err := os.WriteFile(configPath, []byte(tt.yamlConfig), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			defer os.Remove(configPath)
+
+			cfg, _, err := LoadConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(cfg.Profiles) != tt.wantCount {
+				t.Errorf("len(cfg.Profiles) = %d, want %d", len(cfg.Profiles), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestDaemonRunProfileDryRun(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-daemon-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(sourceFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.BackupDirPath = filepath.Join(tmpDir, "backups")
+
+	d := NewDaemon(tmpDir, cfg, DaemonOptions{DryRun: true})
+	d.runProfile(Profile{Name: "notes", Paths: []string{sourceFile}, Schedule: "0 * * * *"})
+
+	backups, err := ListBackups(cfg.BackupDirPath, sourceFile)
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 0 {
+		t.Errorf("expected a dry-run profile to create no backups, got %d", len(backups))
+	}
+}
@@ -0,0 +1,91 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigDiagnostics(t *testing.T) {
+	originalEnv := os.Getenv("BKPFILE_CONFIG")
+	defer func() {
+		if originalEnv != "" {
+			os.Setenv("BKPFILE_CONFIG", originalEnv)
+		} else {
+			os.Unsetenv("BKPFILE_CONFIG")
+		}
+	}()
+
+	tmpDir, err := os.MkdirTemp("", "bkpfile-diag-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	t.Run("missing file named in BKPFILE_CONFIG produces one warning", func(t *testing.T) {
+		missingPath := filepath.Join(tmpDir, "missing.yml")
+		os.Setenv("BKPFILE_CONFIG", missingPath)
+		defer os.Unsetenv("BKPFILE_CONFIG")
+
+		_, diags, err := LoadConfig(tmpDir)
+		if err != nil {
+			t.Fatalf("LoadConfig() unexpected error: %v", err)
+		}
+
+		warnings := diags.Warnings()
+		if len(warnings) != 1 {
+			t.Fatalf("expected exactly one warning diagnostic, got %d: %+v", len(warnings), warnings)
+		}
+		if warnings[0].Path != missingPath {
+			t.Errorf("warning Path = %q, want %q", warnings[0].Path, missingPath)
+		}
+	})
+
+	t.Run("unknown top-level key produces a warning", func(t *testing.T) {
+		os.Unsetenv("BKPFILE_CONFIG")
+		configPath := filepath.Join(tmpDir, ".bkpfile.yml")
+		if err := os.WriteFile(configPath, []byte("not_a_real_key: true\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		defer os.Remove(configPath)
+
+		_, diags, err := LoadConfig(tmpDir)
+		if err != nil {
+			t.Fatalf("LoadConfig() unexpected error: %v", err)
+		}
+
+		found := false
+		for _, w := range diags.Warnings() {
+			if w.Summary == `unknown configuration key "not_a_real_key"` {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected a warning about the unknown key, got %+v", diags.Warnings())
+		}
+	})
+
+	t.Run("deprecated key produces a warning pointing at the replacement", func(t *testing.T) {
+		os.Unsetenv("BKPFILE_CONFIG")
+		configPath := filepath.Join(tmpDir, ".bkpfile.yml")
+		if err := os.WriteFile(configPath, []byte("backup_dir: /tmp/backups\n"), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+		defer os.Remove(configPath)
+
+		_, diags, err := LoadConfig(tmpDir)
+		if err != nil {
+			t.Fatalf("LoadConfig() unexpected error: %v", err)
+		}
+
+		found := false
+		for _, w := range diags.Warnings() {
+			if w.Summary == `"backup_dir" is deprecated, use "backup_dir_path" instead` {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("expected a deprecation warning, got %+v", diags.Warnings())
+		}
+	})
+}
@@ -0,0 +1,394 @@
+package bkpfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+)
+
+// encryptedSuffix is appended to backup filenames when age encryption is
+// enabled. gpgSuffix is its gpg-mode counterpart.
+const (
+	encryptedSuffix = ".age"
+	gpgSuffix       = ".gpg"
+)
+
+// EncryptionConfig controls whether created backups are encrypted at rest
+// Architecture: Data Objects - EncryptionConfig
+type EncryptionConfig struct {
+	// Enabled turns on the age encryption stage in CreateBackup. Deprecated
+	// in favor of Mode, but still honored (as mode "age") for configs
+	// written before Mode existed.
+	// Architecture: EncryptionConfig.Enabled
+	Enabled bool `yaml:"enabled"`
+
+	// Mode selects the encryption backend: "none" (the default), "age", or
+	// "gpg". An empty Mode falls back to Enabled for backward compatibility.
+	// Architecture: EncryptionConfig.Mode
+	Mode string `yaml:"mode"`
+
+	// Recipients is a list of recipients to encrypt backups for: age X25519
+	// public keys or SSH public keys in age mode, gpg key IDs/emails/
+	// fingerprints in gpg mode.
+	// Architecture: EncryptionConfig.Recipients
+	Recipients []string `yaml:"recipients"`
+
+	// PassphraseEnv names an environment variable holding a symmetric
+	// age passphrase. Ignored in gpg mode.
+	// Architecture: EncryptionConfig.PassphraseEnv
+	PassphraseEnv string `yaml:"passphrase_env"`
+
+	// PassphraseFile names a file holding a symmetric age passphrase,
+	// trimmed of a trailing newline. Checked before PassphraseEnv. Ignored
+	// in gpg mode.
+	// Architecture: EncryptionConfig.PassphraseFile
+	PassphraseFile string `yaml:"passphrase_file"`
+
+	// IdentityFile names an age identity file (an X25519 or SSH private
+	// key) to decrypt with, alongside any configured passphrase. This is
+	// what lets isIdenticalToBackup decrypt recipient-encrypted backups
+	// instead of treating them as opaque. Ignored in gpg mode, where
+	// decryption relies on the local gpg secret keyring instead.
+	// Architecture: EncryptionConfig.IdentityFile
+	IdentityFile string `yaml:"identity_file"`
+}
+
+// mode normalizes Mode, falling back to Enabled for configs written before
+// Mode existed. An unrecognized Mode is returned as-is so validate can
+// reject it.
+func (e EncryptionConfig) mode() string {
+	switch e.Mode {
+	case "":
+		if e.Enabled {
+			return "age"
+		}
+		return "none"
+	default:
+		return e.Mode
+	}
+}
+
+// suffix returns the filename suffix CreateBackup/CreateBackupWithTime
+// append to an encrypted backup's name for the configured mode.
+func (e EncryptionConfig) suffix() string {
+	switch e.mode() {
+	case "age":
+		return encryptedSuffix
+	case "gpg":
+		return gpgSuffix
+	default:
+		return ""
+	}
+}
+
+// validate reports an error if Mode (after defaulting via mode()) is not
+// one of the recognized encryption backends.
+// Architecture: Core Functions - Encryption - EncryptionConfig.validate
+func (e EncryptionConfig) validate() error {
+	switch e.mode() {
+	case "none", "age", "gpg":
+		return nil
+	default:
+		return fmt.Errorf("encryption.mode must be one of none, age, gpg, got %q", e.Mode)
+	}
+}
+
+// isEncryptedBackupName reports whether name carries an age or gpg
+// encryption suffix.
+func isEncryptedBackupName(name string) bool {
+	return strings.HasSuffix(name, encryptedSuffix) || strings.HasSuffix(name, gpgSuffix)
+}
+
+// trimEncryptedSuffix strips a trailing age or gpg encryption suffix from
+// name, leaving it unchanged if neither is present.
+func trimEncryptedSuffix(name string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(name, encryptedSuffix), gpgSuffix)
+}
+
+// resolvePassphrase returns the configured symmetric age passphrase, if
+// any, checking PassphraseFile before PassphraseEnv. ok is false when
+// neither is configured.
+func resolvePassphrase(cfg *Config) (passphrase string, ok bool, err error) {
+	if cfg.Encryption.PassphraseFile != "" {
+		data, err := os.ReadFile(cfg.Encryption.PassphraseFile)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read passphrase_file %s: %w", cfg.Encryption.PassphraseFile, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	}
+	if cfg.Encryption.PassphraseEnv != "" {
+		passphrase := os.Getenv(cfg.Encryption.PassphraseEnv)
+		if passphrase == "" {
+			return "", false, fmt.Errorf("environment variable %s is empty or unset", cfg.Encryption.PassphraseEnv)
+		}
+		return passphrase, true, nil
+	}
+	return "", false, nil
+}
+
+// resolveRecipients parses the configured recipients and/or passphrase into
+// age.Recipient values usable with age.Encrypt.
+// Architecture: Core Functions - Encryption - resolveRecipients
+func resolveRecipients(cfg *Config) ([]age.Recipient, error) {
+	var recipients []age.Recipient
+
+	for _, r := range cfg.Encryption.Recipients {
+		if strings.HasPrefix(r, "ssh-") {
+			recipient, err := agessh.ParseRecipient(r)
+			if err != nil {
+				return nil, fmt.Errorf("invalid SSH recipient %q: %w", r, err)
+			}
+			recipients = append(recipients, recipient)
+			continue
+		}
+		recipient, err := age.ParseX25519Recipient(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid age recipient %q: %w", r, err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	passphrase, ok, err := resolvePassphrase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		recipient, err := age.NewScryptRecipient(passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build passphrase recipient: %w", err)
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("encryption is enabled but no recipients, passphrase_env, or passphrase_file are configured")
+	}
+
+	return recipients, nil
+}
+
+// resolveIdentities gathers the age.Identity values usable to decrypt a
+// backup encrypted for cfg.Encryption: the IdentityFile's keys, if any,
+// plus a passphrase identity, if a passphrase is configured.
+func resolveIdentities(cfg *Config) ([]age.Identity, error) {
+	var identities []age.Identity
+
+	if cfg.Encryption.IdentityFile != "" {
+		f, err := os.Open(cfg.Encryption.IdentityFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open identity_file %s: %w", cfg.Encryption.IdentityFile, err)
+		}
+		defer f.Close()
+		parsed, err := age.ParseIdentities(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse identity_file %s: %w", cfg.Encryption.IdentityFile, err)
+		}
+		identities = append(identities, parsed...)
+	}
+
+	passphrase, ok, err := resolvePassphrase(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build passphrase identity: %w", err)
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+// CopyFileEncrypted copies src to dst, encrypting the contents for
+// cfg.Encryption's configured mode and recipients.
+// Architecture: Core Functions - File System Operations - CopyFileEncrypted
+func CopyFileEncrypted(cfg *Config, src, dst string) error {
+	if cfg.Encryption.mode() == "gpg" {
+		return copyFileEncryptedGPG(cfg, src, dst)
+	}
+	return copyFileEncryptedAge(cfg, src, dst)
+}
+
+// copyFileEncryptedAge copies src to dst, encrypting the contents for the
+// recipients configured in cfg.Encryption using filippo.io/age's streaming
+// Encrypt writer.
+func copyFileEncryptedAge(cfg *Config, src, dst string) error {
+	recipients, err := resolveRecipients(cfg)
+	if err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to read source file: %w", err)
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write destination file: %w", err)
+	}
+	defer out.Close()
+
+	encWriter, err := age.Encrypt(out, recipients...)
+	if err != nil {
+		return fmt.Errorf("failed to start encryption stream: %w", err)
+	}
+
+	if _, err := io.Copy(encWriter, in); err != nil {
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+
+	return encWriter.Close()
+}
+
+// copyFileEncryptedGPG copies src to dst, shelling out to a single
+// `gpg --encrypt` run that names every one of cfg.Encryption.Recipients.
+func copyFileEncryptedGPG(cfg *Config, src, dst string) error {
+	if len(cfg.Encryption.Recipients) == 0 {
+		return fmt.Errorf("encryption is enabled but no recipients are configured")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return fmt.Errorf("failed to create destination directory: %w", err)
+	}
+
+	args := []string{"--batch", "--yes", "--trust-model", "always", "--output", dst, "--encrypt"}
+	for _, r := range cfg.Encryption.Recipients {
+		args = append(args, "--recipient", r)
+	}
+	args = append(args, src)
+
+	cmd := exec.Command("gpg", args...)
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("gpg encryption failed: %w", err)
+	}
+	return nil
+}
+
+// decryptAndHash decrypts an encrypted backup at path and returns the hex
+// digest of its plaintext contents under cfg.HashAlgorithm, dispatching to
+// the age or gpg decryptor by the path's suffix. It returns ok=false when
+// no usable identity is configured (age) or no usable secret key is
+// available (gpg), in which case the caller should treat the encrypted
+// backup as opaque.
+// Architecture: Core Functions - Encryption - decryptAndHash
+func decryptAndHash(cfg *Config, path string) (hash string, ok bool, err error) {
+	if strings.HasSuffix(path, gpgSuffix) {
+		return decryptAndHashGPG(cfg, path)
+	}
+	return decryptAndHashAge(cfg, path)
+}
+
+func decryptAndHashAge(cfg *Config, path string) (hash string, ok bool, err error) {
+	identities, err := resolveIdentities(cfg)
+	if err != nil {
+		return "", false, err
+	}
+	if len(identities) == 0 {
+		return "", false, nil
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to open encrypted backup: %w", err)
+	}
+	defer in.Close()
+
+	plain, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+
+	sum, err := hashReader(cfg.HashAlgorithm, plain)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to hash decrypted backup: %w", err)
+	}
+	return sum, true, nil
+}
+
+// decryptAndHashGPG shells out to `gpg --decrypt`, relying on the local gpg
+// secret keyring to find a usable key. A failure here (no secret key, gpg
+// not installed) is treated as "no identity available" rather than an
+// error, matching decryptAndHashAge's behavior for a backup with no
+// configured passphrase.
+func decryptAndHashGPG(cfg *Config, path string) (hash string, ok bool, err error) {
+	out, err := exec.Command("gpg", "--batch", "--yes", "--decrypt", path).Output()
+	if err != nil {
+		return "", false, nil
+	}
+	sum, err := hashReader(cfg.HashAlgorithm, bytes.NewReader(out))
+	if err != nil {
+		return "", false, err
+	}
+	return sum, true, nil
+}
+
+// decryptFile decrypts an encrypted backup at src into dst, dispatching to
+// the age or gpg decryptor by src's suffix. Unlike decryptAndHash, failing
+// to resolve an identity/secret key is a hard error here: restoring
+// without the plaintext isn't a fallback worth having.
+// Architecture: Core Functions - Encryption - decryptFile
+func decryptFile(cfg *Config, src, dst string) error {
+	if strings.HasSuffix(src, gpgSuffix) {
+		return decryptFileGPG(src, dst)
+	}
+	return decryptFileAge(cfg, src, dst)
+}
+
+func decryptFileAge(cfg *Config, src, dst string) error {
+	identities, err := resolveIdentities(cfg)
+	if err != nil {
+		return err
+	}
+	if len(identities) == 0 {
+		return fmt.Errorf("cannot decrypt %s: no identity_file, passphrase_env, or passphrase_file is configured", src)
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open encrypted backup: %w", err)
+	}
+	defer in.Close()
+
+	plain, err := age.Decrypt(in, identities...)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write decrypted output: %w", err)
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, plain); err != nil {
+		return fmt.Errorf("failed to write decrypted output: %w", err)
+	}
+	return nil
+}
+
+func decryptFileGPG(src, dst string) error {
+	out, err := exec.Command("gpg", "--batch", "--yes", "--decrypt", src).Output()
+	if err != nil {
+		return fmt.Errorf("gpg decryption failed: %w", err)
+	}
+	if err := os.WriteFile(dst, out, 0644); err != nil {
+		return fmt.Errorf("failed to write decrypted output: %w", err)
+	}
+	return nil
+}
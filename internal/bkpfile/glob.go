@@ -0,0 +1,234 @@
+package bkpfile
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// matchRule is one compiled gitignore-style pattern: segments is the
+// pattern split on "/", with a leading "**" prepended for patterns that
+// had no "/" of their own, the same way a bare name in a .gitignore
+// matches at any depth.
+type matchRule struct {
+	segments []string
+	negate   bool
+	dirOnly  bool
+}
+
+// compileMatchRule parses a single gitignore-style pattern: a leading "!"
+// negates it, a trailing "/" restricts it to directories, and "**" in any
+// segment matches zero or more path segments.
+func compileMatchRule(pattern string) matchRule {
+	var rule matchRule
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	}
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimSuffix(pattern, "/")
+	}
+	pattern = filepath.ToSlash(pattern)
+	if !strings.Contains(pattern, "/") {
+		pattern = "**/" + pattern
+	}
+	rule.segments = strings.Split(pattern, "/")
+	return rule
+}
+
+// Matcher evaluates a file or directory path against an ordered list of
+// gitignore-style patterns: the last rule that matches wins, so a later
+// "!" pattern can re-include something an earlier pattern excluded.
+// Architecture: Core Types - Matcher
+type Matcher struct {
+	rules []matchRule
+}
+
+// NewMatcher compiles patterns into a Matcher. A pattern with no "/"
+// (other than an optional trailing one) matches at any depth, exactly
+// like a bare name in a .gitignore; "**" matches any number of path
+// segments; a trailing "/" restricts the pattern to directories.
+// Architecture: Core Functions - Glob Matching - NewMatcher
+func NewMatcher(patterns []string) *Matcher {
+	m := &Matcher{}
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+		m.rules = append(m.rules, compileMatchRule(p))
+	}
+	return m
+}
+
+// Match reports whether path (slash- or OS-separator-delimited, relative)
+// is matched by m. isDir tells Match whether path itself names a
+// directory, which matters for dir-only ("pattern/") rules.
+// Architecture: Core Functions - Glob Matching - Matcher.Match
+func (m *Matcher) Match(path string, isDir bool) bool {
+	segments := strings.Split(filepath.ToSlash(path), "/")
+	matched := false
+	for _, rule := range m.rules {
+		if ruleMatchesPath(rule, segments, isDir) {
+			matched = !rule.negate
+		}
+	}
+	return matched
+}
+
+// ruleMatchesPath reports whether rule matches path or any ancestor
+// directory of path, so excluding a directory (with or without a trailing
+// "/") also excludes everything underneath it.
+func ruleMatchesPath(rule matchRule, segments []string, isDir bool) bool {
+	for depth := 1; depth <= len(segments); depth++ {
+		isAncestor := depth < len(segments)
+		if rule.dirOnly && !isAncestor && !isDir {
+			continue
+		}
+		if matchSegments(rule.segments, segments[:depth]) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchSegments matches a compiled pattern's segments against a path's
+// segments, with "**" matching zero or more path segments and every
+// other segment matched via filepath.Match (so *, ?, and [...] work as
+// usual within a single path component).
+func matchSegments(pattern, path []string) bool {
+	if len(pattern) == 0 {
+		return len(path) == 0
+	}
+	if pattern[0] == "**" {
+		if len(pattern) == 1 {
+			return true
+		}
+		for i := 0; i <= len(path); i++ {
+			if matchSegments(pattern[1:], path[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(path) == 0 {
+		return false
+	}
+	ok, err := filepath.Match(pattern[0], path[0])
+	if err != nil || !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], path[1:])
+}
+
+// ExpandPattern expands a shell-style glob into the list of regular files
+// it matches. Patterns without "**" are expanded with filepath.Glob
+// directly; a pattern containing "**" is expanded by walking the
+// directory tree rooted at the pattern's static prefix (the path segments
+// before the first "**") and matching each file's path against the full
+// pattern. The returned paths are relative to the current directory and
+// sorted for deterministic ordering.
+// Architecture: Core Functions - Glob Matching - ExpandPattern
+func ExpandPattern(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+		return onlyRegularFiles(matches), nil
+	}
+
+	slashPattern := filepath.ToSlash(pattern)
+	segments := strings.Split(slashPattern, "/")
+	root := "."
+	prefixLen := 0
+	for prefixLen < len(segments) && segments[prefixLen] != "**" {
+		prefixLen++
+	}
+	if prefixLen > 0 {
+		root = filepath.FromSlash(strings.Join(segments[:prefixLen], "/"))
+		if root == "" {
+			root = string(filepath.Separator)
+		}
+	}
+
+	rule := matchRule{segments: segments}
+
+	var matches []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		pathSegments := strings.Split(filepath.ToSlash(path), "/")
+		if matchSegments(rule.segments, pathSegments) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand pattern %q: %w", pattern, err)
+	}
+
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// onlyRegularFiles filters out directories (and anything that no longer
+// stats cleanly) from a filepath.Glob result.
+func onlyRegularFiles(paths []string) []string {
+	var out []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CreateBackupGlob expands pattern into the files it matches, filters
+// them with gitignore-style include/exclude rules (see Matcher), and
+// backs up each surviving file with CreateBackups, giving one backup per
+// matched file under the existing per-file directory layout. A file must
+// match at least one include pattern (if any were given) and must not
+// match any exclude pattern; exclude patterns are evaluated in order, so
+// a later "!" exclude pattern can carve a file back out of an excluded
+// directory.
+// Architecture: Core Functions - Backup Management - CreateBackupGlob
+func CreateBackupGlob(cfg *Config, pattern string, include, exclude []string, note string, dryRun bool) ([]BackupResult, error) {
+	candidates, err := ExpandPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	var includeMatcher *Matcher
+	if len(include) > 0 {
+		includeMatcher = NewMatcher(include)
+	}
+	excludeMatcher := NewMatcher(exclude)
+
+	var selected []string
+	for _, path := range candidates {
+		if includeMatcher != nil && !includeMatcher.Match(path, false) {
+			continue
+		}
+		if excludeMatcher.Match(path, false) {
+			continue
+		}
+		selected = append(selected, path)
+	}
+
+	if len(selected) == 0 {
+		return nil, nil
+	}
+
+	return CreateBackups(cfg, selected, note, dryRun, 0)
+}
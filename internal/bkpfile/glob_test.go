@@ -0,0 +1,140 @@
+package bkpfile
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{
+			name:     "bare name matches at any depth",
+			patterns: []string{"vendor"},
+			path:     "src/vendor/lib.go",
+			want:     true,
+		},
+		{
+			name:     "dir-only pattern excludes contents but not a same-named file",
+			patterns: []string{"vendor/"},
+			path:     "vendor",
+			isDir:    false,
+			want:     false,
+		},
+		{
+			name:     "dir-only pattern matches the directory itself",
+			patterns: []string{"vendor/"},
+			path:     "vendor",
+			isDir:    true,
+			want:     true,
+		},
+		{
+			name:     "dir-only pattern matches files underneath",
+			patterns: []string{"vendor/"},
+			path:     "vendor/pkg/lib.go",
+			want:     true,
+		},
+		{
+			name:     "double star matches recursive go files",
+			patterns: []string{"src/**/*.go"},
+			path:     "src/a/b/c.go",
+			want:     true,
+		},
+		{
+			name:     "double star pattern does not match outside its root",
+			patterns: []string{"src/**/*.go"},
+			path:     "other/c.go",
+			want:     false,
+		},
+		{
+			name:     "negation re-includes a file excluded by an earlier rule",
+			patterns: []string{"vendor/", "!vendor/keep.go"},
+			path:     "vendor/keep.go",
+			want:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMatcher(tt.patterns)
+			if got := m.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Matcher.Match(%q, isDir=%v) with patterns %v = %v, want %v", tt.path, tt.isDir, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcherNegationCarvesOutSubset(t *testing.T) {
+	m := NewMatcher([]string{"vendor/", "!vendor/keep.go"})
+	if m.Match("vendor/keep.go", false) {
+		t.Error("expected the negation to carve vendor/keep.go back out of the excluded vendor/")
+	}
+	if !m.Match("vendor/other.go", false) {
+		t.Error("expected vendor/other.go to remain excluded")
+	}
+
+	excludeThenReinclude := NewMatcher([]string{"!vendor/keep.go", "vendor/"})
+	if !excludeThenReinclude.Match("vendor/keep.go", false) {
+		t.Error("expected the later exclude rule to win over the earlier negation")
+	}
+}
+
+func TestExpandPatternDoubleStar(t *testing.T) {
+	env, _ := newTestEnv(t)
+
+	env.CreateFile("src/a.go", "a")
+	env.CreateFile("src/nested/b.go", "b")
+	env.CreateFile("src/nested/deep/c.go", "c")
+	env.CreateFile("src/readme.md", "not go")
+	env.CreateFile("other/d.go", "d")
+
+	got, err := ExpandPattern("src/**/*.go")
+	if err != nil {
+		t.Fatalf("ExpandPattern() error: %v", err)
+	}
+
+	want := []string{"src/a.go", "src/nested/b.go", "src/nested/deep/c.go"}
+	sort.Strings(want)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandPattern() = %v, want %v", got, want)
+	}
+}
+
+func TestCreateBackupGlobWithExcludes(t *testing.T) {
+	env, _ := newTestEnv(t)
+
+	env.CreateFile("src/a.go", "a")
+	env.CreateFile("src/vendor/b.go", "b")
+	env.CreateFile("src/vendor/keep.go", "keep")
+
+	cfg, _, err := env.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	results, err := CreateBackupGlob(cfg, "src/**/*.go", nil, []string{"src/vendor/", "!src/vendor/keep.go"}, "glob", false)
+	if err != nil {
+		t.Fatalf("CreateBackupGlob() error: %v", err)
+	}
+
+	var backedUp []string
+	for _, result := range results {
+		if result.Err != nil {
+			t.Errorf("CreateBackupGlob() result for %s: %v", result.Path, result.Err)
+		}
+		backedUp = append(backedUp, result.Path)
+	}
+	sort.Strings(backedUp)
+
+	want := []string{"src/a.go", "src/vendor/keep.go"}
+	if !reflect.DeepEqual(backedUp, want) {
+		t.Errorf("CreateBackupGlob() backed up %v, want %v", backedUp, want)
+	}
+}
@@ -0,0 +1,68 @@
+package bkpfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/spf13/afero"
+	"golang.org/x/crypto/blake2b"
+)
+
+// Recognized values for Config.HashAlgorithm.
+const (
+	HashAlgorithmSHA256  = "sha256"
+	HashAlgorithmXXHash  = "xxhash"
+	HashAlgorithmBLAKE2b = "blake2b"
+)
+
+// newHasher returns a hash.Hash for algo, defaulting to SHA-256 when algo
+// is "" so a zero-value Config still hashes the way it always has.
+func newHasher(algo string) (hash.Hash, error) {
+	switch algo {
+	case "", HashAlgorithmSHA256:
+		return sha256.New(), nil
+	case HashAlgorithmXXHash:
+		return xxhash.New(), nil
+	case HashAlgorithmBLAKE2b:
+		return blake2b.New256(nil)
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm %q", algo)
+	}
+}
+
+// validateHashAlgorithm reports an error if algo is not one of the
+// recognized hash algorithms (or empty, which defaults to SHA-256).
+// Architecture: Core Functions - Backup Management - validateHashAlgorithm
+func validateHashAlgorithm(algo string) error {
+	_, err := newHasher(algo)
+	return err
+}
+
+// hashReader streams r through the algo hash and returns its hex digest,
+// bounding memory for large inputs the way reading a whole file into
+// memory first would not.
+func hashReader(algo string, r io.Reader) (string, error) {
+	h, err := newHasher(algo)
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// hashFile streams path's contents on fsys through the algo hash and
+// returns its hex digest.
+func hashFile(fsys afero.Fs, algo, path string) (string, error) {
+	f, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return hashReader(algo, f)
+}
@@ -0,0 +1,44 @@
+package bkpfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNewHasherSupportedAlgorithms(t *testing.T) {
+	tests := []string{"", HashAlgorithmSHA256, HashAlgorithmXXHash, HashAlgorithmBLAKE2b}
+	for _, algo := range tests {
+		if _, err := newHasher(algo); err != nil {
+			t.Errorf("newHasher(%q) error = %v, want nil", algo, err)
+		}
+	}
+}
+
+func TestNewHasherUnsupportedAlgorithm(t *testing.T) {
+	if _, err := newHasher("md5"); err == nil {
+		t.Error("newHasher(\"md5\") error = nil, want error for an unrecognized algorithm")
+	}
+}
+
+func TestHashReaderDiffersByAlgorithm(t *testing.T) {
+	sha256Sum, err := hashReader(HashAlgorithmSHA256, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("hashReader(sha256) error = %v", err)
+	}
+	blake2bSum, err := hashReader(HashAlgorithmBLAKE2b, strings.NewReader("hello"))
+	if err != nil {
+		t.Fatalf("hashReader(blake2b) error = %v", err)
+	}
+	if sha256Sum == blake2bSum {
+		t.Error("hashReader() returned the same digest for sha256 and blake2b")
+	}
+}
+
+func TestValidateHashAlgorithm(t *testing.T) {
+	if err := validateHashAlgorithm(HashAlgorithmXXHash); err != nil {
+		t.Errorf("validateHashAlgorithm(%q) error = %v, want nil", HashAlgorithmXXHash, err)
+	}
+	if err := validateHashAlgorithm("unknown"); err == nil {
+		t.Error("validateHashAlgorithm(\"unknown\") error = nil, want error")
+	}
+}
@@ -0,0 +1,237 @@
+package bkpfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// metaSuffix is the sidecar filename extension written alongside every
+// plain/archive/encrypted backup, analogous to cdcManifestSuffix for
+// content-addressed backups.
+const metaSuffix = ".meta.json"
+
+// BackupMeta is the JSON sidecar CreateBackup/CreateBackupWithTime write
+// next to a backup as "<backup>.meta.json": enough to restore original
+// ownership, filter by tag or hash, and let `bkpfile verify` detect
+// corruption without re-reading the source file.
+// Architecture: Data Objects - BackupMeta
+type BackupMeta struct {
+	// AbsoluteSourcePath is the source file's absolute path at backup time.
+	AbsoluteSourcePath string `json:"absolute_source_path"`
+	// RelativeSourcePath is the source file's path relative to the working
+	// directory bkpfile was run from.
+	RelativeSourcePath string `json:"relative_source_path"`
+	// UID is the source file's owning user ID at backup time.
+	UID uint32 `json:"uid"`
+	// GID is the source file's owning group ID at backup time.
+	GID uint32 `json:"gid"`
+	// Mode is the source file's permission bits at backup time.
+	Mode uint32 `json:"mode"`
+	// ModTime is the source file's modification time at backup time.
+	ModTime time.Time `json:"mod_time"`
+	// Size is the stored backup's size in bytes.
+	Size int64 `json:"size"`
+	// Hash is the stored backup's content hash, used by `bkpfile verify`.
+	Hash string `json:"hash"`
+	// HashAlgorithm is the algorithm Hash was computed with: "sha256",
+	// "xxhash", or "blake2b". Sidecars written before HashAlgorithm
+	// existed have no value here and are assumed to be "sha256".
+	HashAlgorithm string `json:"hash_algorithm,omitempty"`
+	// SHA256 is a deprecated alias for Hash, kept so tooling written
+	// against bkpfile before pluggable hash algorithms landed can still
+	// read a sidecar's hash. Only populated when HashAlgorithm is
+	// "sha256". Deprecated: use Hash/HashAlgorithm instead.
+	SHA256 string `json:"sha256,omitempty"`
+	// Hostname is the host bkpfile ran on when the backup was created.
+	Hostname string `json:"hostname"`
+	// Note is the free-form note supplied for this backup, if any.
+	Note string `json:"note,omitempty"`
+	// Tags holds user-supplied "--tag k=v" labels for this backup.
+	Tags map[string]string `json:"tags,omitempty"`
+}
+
+// metaPath returns the sidecar metadata path for a backup file.
+func metaPath(backupPath string) string {
+	return backupPath + metaSuffix
+}
+
+// writeBackupMeta stats filePath and backupPath and writes the resulting
+// BackupMeta to backupPath's sidecar. It must be called after backupPath
+// has been fully written, since Size/Hash describe the stored bytes. The
+// hash is streamed through algo via io.Copy rather than read into memory,
+// so it stays cheap for large backups.
+func writeBackupMeta(fsys afero.Fs, filePath, backupPath, note string, tags map[string]string, algo string) error {
+	absSourcePath, err := filepath.Abs(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute source path: %w", err)
+	}
+	relSourcePath := filePath
+	if !filepath.IsAbs(filePath) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, absSourcePath); err == nil {
+				relSourcePath = rel
+			}
+		}
+	}
+
+	srcInfo, err := fsys.Stat(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to stat source file for metadata: %w", err)
+	}
+	var uid, gid uint32
+	if stat, ok := srcInfo.Sys().(*syscall.Stat_t); ok {
+		uid, gid = stat.Uid, stat.Gid
+	}
+
+	backupInfo, err := fsys.Stat(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat backup for metadata: %w", err)
+	}
+	hash, err := hashFile(fsys, algo, backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to hash backup for metadata: %w", err)
+	}
+
+	meta := BackupMeta{
+		AbsoluteSourcePath: absSourcePath,
+		RelativeSourcePath: relSourcePath,
+		UID:                uid,
+		GID:                gid,
+		Mode:               uint32(srcInfo.Mode().Perm()),
+		ModTime:            srcInfo.ModTime(),
+		Size:               backupInfo.Size(),
+		Hash:               hash,
+		HashAlgorithm:      algo,
+		Hostname:           hostname(),
+		Note:               note,
+		Tags:               tags,
+	}
+	if algo == "" || algo == HashAlgorithmSHA256 {
+		meta.SHA256 = hash
+	}
+
+	out, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal backup metadata: %w", err)
+	}
+	if err := afero.WriteFile(fsys, metaPath(backupPath), out, 0644); err != nil {
+		return fmt.Errorf("failed to write backup metadata: %w", err)
+	}
+	return nil
+}
+
+// readBackupMeta reads the sidecar metadata for backupPath, if one exists.
+// A missing sidecar is not an error: it returns (nil, nil) so callers can
+// treat older backups (created before metadata.go landed) as simply
+// lacking metadata.
+func readBackupMeta(fsys afero.Fs, backupPath string) (*BackupMeta, error) {
+	exists, err := afero.Exists(fsys, metaPath(backupPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check backup metadata: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	data, err := afero.ReadFile(fsys, metaPath(backupPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup metadata: %w", err)
+	}
+	var meta BackupMeta
+	if err := json.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("failed to parse backup metadata %s: %w", metaPath(backupPath), err)
+	}
+	// Sidecars written before Hash/HashAlgorithm existed only have SHA256;
+	// treat that the same as a sha256-algorithm Hash.
+	if meta.Hash == "" && meta.SHA256 != "" {
+		meta.Hash = meta.SHA256
+		meta.HashAlgorithm = HashAlgorithmSHA256
+	}
+	if meta.HashAlgorithm == "" {
+		meta.HashAlgorithm = HashAlgorithmSHA256
+	}
+	return &meta, nil
+}
+
+// hostname returns os.Hostname(), or "" if it cannot be determined.
+func hostname() string {
+	h, err := os.Hostname()
+	if err != nil {
+		return ""
+	}
+	return h
+}
+
+// VerifyBackup rehashes the stored bytes of backupPath, streaming them
+// through the hash algorithm recorded in its sidecar BackupMeta, and
+// reports whether the result still matches the hash recorded at backup
+// time. It returns an error if the backup has no metadata sidecar to
+// verify against.
+// Architecture: Core Functions - Backup Management - VerifyBackup
+func VerifyBackup(cfg *Config, backupPath string) (ok bool, err error) {
+	fsys := cfg.fs()
+
+	meta, err := readBackupMeta(fsys, backupPath)
+	if err != nil {
+		return false, err
+	}
+	if meta == nil {
+		return false, fmt.Errorf("no metadata sidecar for %s; cannot verify", backupPath)
+	}
+
+	hash, err := hashFile(fsys, meta.HashAlgorithm, backupPath)
+	if err != nil {
+		return false, fmt.Errorf("failed to hash backup: %w", err)
+	}
+	return hash == meta.Hash, nil
+}
+
+// VerifyBackupTree walks dir (typically cfg.BackupDirPath or a subtree of
+// it) and calls VerifyBackup on every backup file found, skipping sidecar
+// metadata, chunk manifests, and directories themselves. It returns one
+// BackupVerifyResult per backup it attempted to verify; a backup with no
+// metadata sidecar is reported with its VerifyBackup error rather than
+// stopping the walk, so one unverifiable backup doesn't hide corruption
+// elsewhere in the tree.
+// Architecture: Core Functions - Backup Management - VerifyBackupTree
+func VerifyBackupTree(cfg *Config, dir string) ([]BackupVerifyResult, error) {
+	fsys := cfg.fs()
+
+	var results []BackupVerifyResult
+	err := afero.Walk(fsys, dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || strings.HasSuffix(path, metaSuffix) || strings.HasSuffix(path, cdcManifestSuffix) {
+			return nil
+		}
+
+		ok, verifyErr := VerifyBackup(cfg, path)
+		results = append(results, BackupVerifyResult{Path: path, OK: ok, Err: verifyErr})
+		return nil
+	})
+	if err != nil {
+		return results, fmt.Errorf("failed to walk backup tree %s: %w", dir, err)
+	}
+	return results, nil
+}
+
+// BackupVerifyResult is one backup's outcome from VerifyBackupTree.
+// Architecture: Data Objects - BackupVerifyResult
+type BackupVerifyResult struct {
+	// Path is the backup file that was checked.
+	Path string
+	// OK reports whether the backup's rehashed contents matched its
+	// recorded hash. Meaningless when Err is set.
+	OK bool
+	// Err is the error VerifyBackup returned for Path, typically because
+	// it has no metadata sidecar to verify against.
+	Err error
+}
@@ -0,0 +1,226 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateBackupWithTagsRecordsMetadata(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-meta-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(sourceFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.BackupDirPath = filepath.Join(tmpDir, "backups")
+
+	tags := map[string]string{"env": "prod", "owner": "ops"}
+	err = CreateBackupWithTags(cfg, sourceFile, "note", tags, false)
+	if backupErr, ok := err.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusCreatedBackup {
+		t.Fatalf("CreateBackupWithTags() error = %v", err)
+	}
+
+	backups, err := ListBackups(cfg.BackupDirPath, sourceFile)
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("ListBackups() returned %d backups, want 1", len(backups))
+	}
+
+	backup := backups[0]
+	if backup.SHA256 == "" {
+		t.Errorf("expected backup.SHA256 to be populated from metadata")
+	}
+	if backup.Tags["env"] != "prod" || backup.Tags["owner"] != "ops" {
+		t.Errorf("backup.Tags = %v, want %v", backup.Tags, tags)
+	}
+
+	meta, err := readBackupMeta(cfg.fs(), backup.Path)
+	if err != nil {
+		t.Fatalf("readBackupMeta() error = %v", err)
+	}
+	if meta == nil {
+		t.Fatalf("readBackupMeta() returned nil metadata")
+	}
+	if meta.AbsoluteSourcePath != sourceFile {
+		t.Errorf("metadata.AbsoluteSourcePath = %q, want %q", meta.AbsoluteSourcePath, sourceFile)
+	}
+	if meta.Size != int64(len("hello")) {
+		t.Errorf("metadata.Size = %d, want %d", meta.Size, len("hello"))
+	}
+
+	ok, err := VerifyBackup(cfg, backup.Path)
+	if err != nil {
+		t.Fatalf("VerifyBackup() error = %v", err)
+	}
+	if !ok {
+		t.Errorf("VerifyBackup() = false, want true for an untouched backup")
+	}
+}
+
+func TestVerifyBackupDetectsCorruption(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-meta-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(sourceFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.BackupDirPath = filepath.Join(tmpDir, "backups")
+
+	err = CreateBackup(cfg, sourceFile, "", false)
+	if backupErr, ok := err.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusCreatedBackup {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+
+	backups, err := ListBackups(cfg.BackupDirPath, sourceFile)
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("ListBackups() returned %d backups, want 1", len(backups))
+	}
+
+	if err := os.WriteFile(backups[0].Path, []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt backup: %v", err)
+	}
+
+	ok, err := VerifyBackup(cfg, backups[0].Path)
+	if err != nil {
+		t.Fatalf("VerifyBackup() error = %v", err)
+	}
+	if ok {
+		t.Errorf("VerifyBackup() = true, want false for a corrupted backup")
+	}
+}
+
+func TestVerifyBackupWithoutMetadataErrors(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-meta-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	backupPath := filepath.Join(tmpDir, "orphan.txt")
+	if err := os.WriteFile(backupPath, []byte("data"), 0644); err != nil {
+		t.Fatalf("Failed to seed backup without metadata: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	if _, err := VerifyBackup(cfg, backupPath); err == nil {
+		t.Errorf("expected VerifyBackup() to error for a backup with no metadata sidecar")
+	}
+}
+
+func TestCreateBackupWithBlake2bAlgorithm(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-meta-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(sourceFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.BackupDirPath = filepath.Join(tmpDir, "backups")
+	cfg.HashAlgorithm = HashAlgorithmBLAKE2b
+
+	err = CreateBackup(cfg, sourceFile, "", false)
+	if backupErr, ok := err.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusCreatedBackup {
+		t.Fatalf("CreateBackup() error = %v", err)
+	}
+
+	backups, err := ListBackups(cfg.BackupDirPath, sourceFile)
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("ListBackups() returned %d backups, want 1", len(backups))
+	}
+
+	backup := backups[0]
+	if backup.HashAlgorithm != HashAlgorithmBLAKE2b {
+		t.Errorf("backup.HashAlgorithm = %q, want %q", backup.HashAlgorithm, HashAlgorithmBLAKE2b)
+	}
+	if backup.SHA256 != "" {
+		t.Errorf("backup.SHA256 = %q, want empty for a non-sha256 algorithm", backup.SHA256)
+	}
+
+	ok, err := VerifyBackup(cfg, backup.Path)
+	if err != nil {
+		t.Fatalf("VerifyBackup() error = %v", err)
+	}
+	if !ok {
+		t.Errorf("VerifyBackup() = false, want true for an untouched backup")
+	}
+
+	// Re-running CreateBackup against the identical source should still
+	// short-circuit via the recorded hash rather than creating a duplicate.
+	err = CreateBackup(cfg, sourceFile, "", false)
+	if backupErr, ok := err.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusFileIsIdenticalToExistingBackup {
+		t.Fatalf("second CreateBackup() error = %v, want StatusFileIsIdenticalToExistingBackup", err)
+	}
+}
+
+func TestVerifyBackupTree(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-meta-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	sourceFile := filepath.Join(tmpDir, "notes.txt")
+	if err := os.WriteFile(sourceFile, []byte("hello"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.BackupDirPath = filepath.Join(tmpDir, "backups")
+
+	if err := CreateBackup(cfg, sourceFile, "good", false); err != nil {
+		if backupErr, ok := err.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusCreatedBackup {
+			t.Fatalf("CreateBackup() error = %v", err)
+		}
+	}
+
+	backups, err := ListBackups(cfg.BackupDirPath, sourceFile)
+	if err != nil {
+		t.Fatalf("ListBackups() error = %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("ListBackups() returned %d backups, want 1", len(backups))
+	}
+	if err := os.WriteFile(backups[0].Path, []byte("corrupted"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt backup: %v", err)
+	}
+
+	results, err := VerifyBackupTree(cfg, cfg.BackupDirPath)
+	if err != nil {
+		t.Fatalf("VerifyBackupTree() error = %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("VerifyBackupTree() returned %d results, want 1", len(results))
+	}
+	if results[0].OK {
+		t.Errorf("VerifyBackupTree() result.OK = true, want false for a corrupted backup")
+	}
+	if results[0].Err != nil {
+		t.Errorf("VerifyBackupTree() result.Err = %v, want nil (corruption isn't an error)", results[0].Err)
+	}
+}
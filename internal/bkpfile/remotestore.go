@@ -0,0 +1,197 @@
+package bkpfile
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// backupCacheDirName is the subdirectory of a Config.BackupDirPath used as
+// the local disk cache in front of a remote RemoteBackupFS selected by
+// Config.BackupURL.
+const backupCacheDirName = ".remote-cache"
+
+// newBackupStoreForURL builds the RemoteBackupFS a Config.BackupURL value
+// selects: "" keeps today's local-directory behavior, and "s3://",
+// "sftp://", "webdav://" and "webdavs://" each front a remote backend with
+// NewCachingBackupStore so identical-file detection (isIdenticalToBackup)
+// stays warm without re-downloading from the remote on every backup.
+// Architecture: Core Functions - Backup Storage - newBackupStoreForURL
+func newBackupStoreForURL(rawURL string, fsys afero.Fs, backupDirPath string) (RemoteBackupFS, error) {
+	if rawURL == "" {
+		return newLocalBackupStore(fsys, backupDirPath), nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid backup_url %q: %w", rawURL, err)
+	}
+
+	cache := newLocalBackupStore(fsys, filepath.Join(backupDirPath, backupCacheDirName))
+
+	var remote RemoteBackupFS
+	switch u.Scheme {
+	case "", "file":
+		return newLocalBackupStore(fsys, backupDirPath), nil
+	case "s3":
+		remote, err = newS3BackupStore(u)
+	case "sftp":
+		remote, err = newSFTPBackupStore(u)
+	case "webdav", "webdavs":
+		remote, err = newWebDAVBackupStore(u)
+	default:
+		return nil, fmt.Errorf("unsupported backup_url scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewCachingBackupStore(remote, cache), nil
+}
+
+// RemoteBackupFS is the interface a pluggable backup destination
+// implements. It operates on whole backup blobs (a plain/archive/encrypted
+// backup file, or a CDC chunk/manifest) identified by a key relative to
+// BackupDirPath, rather than exposing a general-purpose filesystem. The
+// local directory implementation below preserves bkpfile's existing
+// behavior; SFTP and S3-compatible object stores are expected to slot in
+// here as additional RemoteBackupFS implementations.
+// Architecture: Core Types - RemoteBackupFS
+type RemoteBackupFS interface {
+	// Put writes data as the blob named key, creating any parent
+	// directories the key implies.
+	Put(key string, data []byte) error
+	// Get returns the bytes stored for key.
+	Get(key string) ([]byte, error)
+	// Stat returns the size and modification time of the blob named key.
+	Stat(key string) (size int64, modTime time.Time, err error)
+	// List returns the keys of every blob stored directly under prefix.
+	List(prefix string) ([]string, error)
+	// Delete removes the blob named key.
+	Delete(key string) error
+}
+
+// localBackupStore is the RemoteBackupFS backed directly by an afero.Fs
+// rooted at a backup directory. It is the default store, and is exactly
+// the filesystem-backed behavior bkpfile has always had.
+// Architecture: Core Types - localBackupStore
+type localBackupStore struct {
+	fs   afero.Fs
+	root string
+}
+
+// newLocalBackupStore returns a RemoteBackupFS that reads and writes blobs
+// directly under root on fs.
+// Architecture: Core Functions - Backup Storage - newLocalBackupStore
+func newLocalBackupStore(fs afero.Fs, root string) *localBackupStore {
+	return &localBackupStore{fs: fs, root: root}
+}
+
+func (s *localBackupStore) path(key string) string {
+	return filepath.Join(s.root, key)
+}
+
+func (s *localBackupStore) Put(key string, data []byte) error {
+	p := s.path(key)
+	if err := s.fs.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return fmt.Errorf("failed to create backup directory: %w", err)
+	}
+	return afero.WriteFile(s.fs, p, data, 0644)
+}
+
+func (s *localBackupStore) Get(key string) ([]byte, error) {
+	return afero.ReadFile(s.fs, s.path(key))
+}
+
+func (s *localBackupStore) Stat(key string) (int64, time.Time, error) {
+	info, err := s.fs.Stat(s.path(key))
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	return info.Size(), info.ModTime(), nil
+}
+
+func (s *localBackupStore) List(prefix string) ([]string, error) {
+	entries, err := afero.ReadDir(s.fs, s.path(prefix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	keys := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		keys = append(keys, filepath.Join(prefix, entry.Name()))
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *localBackupStore) Delete(key string) error {
+	return s.fs.Remove(s.path(key))
+}
+
+// cachingBackupStore wraps a remote RemoteBackupFS with a local disk
+// cache, mirroring afero's CacheOnReadFs/CopyOnWriteFs layering: reads are
+// served from the cache when present and only fall through to the remote
+// store on a miss, and every write goes to both the cache and the remote
+// store, so the cache stays warm for the identical-file detection
+// CreateBackupWithTime relies on.
+// Architecture: Core Types - cachingBackupStore
+type cachingBackupStore struct {
+	remote RemoteBackupFS
+	cache  RemoteBackupFS
+}
+
+// NewCachingBackupStore returns a RemoteBackupFS that serves reads from
+// cache when possible and writes through to both cache and remote.
+// Architecture: Core Functions - Backup Storage - NewCachingBackupStore
+func NewCachingBackupStore(remote, cache RemoteBackupFS) RemoteBackupFS {
+	return &cachingBackupStore{remote: remote, cache: cache}
+}
+
+func (s *cachingBackupStore) Put(key string, data []byte) error {
+	if err := s.remote.Put(key, data); err != nil {
+		return err
+	}
+	return s.cache.Put(key, data)
+}
+
+func (s *cachingBackupStore) Get(key string) ([]byte, error) {
+	if data, err := s.cache.Get(key); err == nil {
+		return data, nil
+	}
+	data, err := s.remote.Get(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.cache.Put(key, data); err != nil {
+		return nil, fmt.Errorf("failed to populate local cache: %w", err)
+	}
+	return data, nil
+}
+
+func (s *cachingBackupStore) Stat(key string) (int64, time.Time, error) {
+	if size, modTime, err := s.cache.Stat(key); err == nil {
+		return size, modTime, nil
+	}
+	return s.remote.Stat(key)
+}
+
+func (s *cachingBackupStore) List(prefix string) ([]string, error) {
+	return s.remote.List(prefix)
+}
+
+func (s *cachingBackupStore) Delete(key string) error {
+	if err := s.remote.Delete(key); err != nil {
+		return err
+	}
+	return s.cache.Delete(key)
+}
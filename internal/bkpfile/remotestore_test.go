@@ -0,0 +1,122 @@
+package bkpfile
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+)
+
+func TestLocalBackupStorePutGetListDelete(t *testing.T) {
+	store := newLocalBackupStore(afero.NewMemMapFs(), "/backups")
+
+	if err := store.Put("notes.txt-2025-05-12-13-49", []byte("hello")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	data, err := store.Get("notes.txt-2025-05-12-13-49")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(data) != "hello" {
+		t.Errorf("Get() = %q, want %q", data, "hello")
+	}
+
+	size, _, err := store.Stat("notes.txt-2025-05-12-13-49")
+	if err != nil {
+		t.Fatalf("Stat() error = %v", err)
+	}
+	if size != int64(len("hello")) {
+		t.Errorf("Stat() size = %d, want %d", size, len("hello"))
+	}
+
+	keys, err := store.List(".")
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(keys) != 1 {
+		t.Fatalf("List() returned %d keys, want 1", len(keys))
+	}
+
+	if err := store.Delete("notes.txt-2025-05-12-13-49"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := store.Get("notes.txt-2025-05-12-13-49"); err == nil {
+		t.Errorf("expected Get() to fail after Delete()")
+	}
+}
+
+func TestCachingBackupStoreServesReadsFromCache(t *testing.T) {
+	remote := newLocalBackupStore(afero.NewMemMapFs(), "/remote")
+	cache := newLocalBackupStore(afero.NewMemMapFs(), "/cache")
+	store := NewCachingBackupStore(remote, cache)
+
+	if err := store.Put("k", []byte("v1")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// Overwrite the remote copy directly so a cache hit is distinguishable
+	// from a fallthrough to the remote store.
+	if err := remote.Put("k", []byte("v2-remote-only")); err != nil {
+		t.Fatalf("remote.Put() error = %v", err)
+	}
+
+	data, err := store.Get("k")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(data) != "v1" {
+		t.Errorf("Get() = %q, want the cached value %q", data, "v1")
+	}
+
+	if err := cache.Delete("k"); err != nil {
+		t.Fatalf("cache.Delete() error = %v", err)
+	}
+
+	data, err = store.Get("k")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if string(data) != "v2-remote-only" {
+		t.Errorf("Get() = %q, want the remote value %q after a cache miss", data, "v2-remote-only")
+	}
+
+	if cached, err := cache.Get("k"); err != nil || string(cached) != "v2-remote-only" {
+		t.Errorf("expected the cache miss to repopulate the cache, got %q, err %v", cached, err)
+	}
+}
+
+func TestNewBackupStoreForURLSelectsBackend(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+
+	store, err := newBackupStoreForURL("", fsys, "/backups")
+	if err != nil {
+		t.Fatalf("newBackupStoreForURL(\"\") error = %v", err)
+	}
+	if _, ok := store.(*localBackupStore); !ok {
+		t.Errorf("newBackupStoreForURL(\"\") = %T, want *localBackupStore", store)
+	}
+
+	store, err = newBackupStoreForURL("file:///backups", fsys, "/backups")
+	if err != nil {
+		t.Fatalf("newBackupStoreForURL(file://) error = %v", err)
+	}
+	if _, ok := store.(*localBackupStore); !ok {
+		t.Errorf("newBackupStoreForURL(file://) = %T, want *localBackupStore", store)
+	}
+
+	if _, err := newBackupStoreForURL("ftp://host/path", fsys, "/backups"); err == nil {
+		t.Error("expected newBackupStoreForURL() to reject an unsupported scheme")
+	}
+
+	if _, err := newBackupStoreForURL("s3:///no-bucket", fsys, "/backups"); err == nil {
+		t.Error("expected newBackupStoreForURL(s3://) to error without a bucket name")
+	}
+
+	if _, err := newBackupStoreForURL("sftp:///no-host", fsys, "/backups"); err == nil {
+		t.Error("expected newBackupStoreForURL(sftp://) to error without a host")
+	}
+
+	if _, err := newBackupStoreForURL("webdav:///no-host", fsys, "/backups"); err == nil {
+		t.Error("expected newBackupStoreForURL(webdav://) to error without a host")
+	}
+}
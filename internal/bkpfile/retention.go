@@ -0,0 +1,352 @@
+package bkpfile
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+// RetentionConfig controls which backups bkpfile.PruneBackups keeps for a
+// given file. KeepLast and KeepWithin are independent filters (a backup
+// survives if either keeps it); KeepHourly/KeepDaily/KeepWeekly/KeepMonthly
+// implement grandfather-father-son style bucketed retention on top of
+// those. MaxTotalBytes and then MinFreeBytes are applied last and can
+// evict backups that the rules above would otherwise have kept.
+// Architecture: Data Objects - RetentionConfig
+type RetentionConfig struct {
+	// KeepLast keeps the N most recent backups regardless of age
+	// Architecture: RetentionConfig.KeepLast
+	KeepLast int `yaml:"keep_last"`
+
+	// KeepWithin keeps every backup newer than this duration, e.g. "7d"
+	// Architecture: RetentionConfig.KeepWithin
+	KeepWithin string `yaml:"keep_within"`
+
+	// KeepHourly keeps the newest backup for each of the last N hours
+	// Architecture: RetentionConfig.KeepHourly
+	KeepHourly int `yaml:"keep_hourly"`
+
+	// KeepDaily keeps the newest backup for each of the last N days
+	// Architecture: RetentionConfig.KeepDaily
+	KeepDaily int `yaml:"keep_daily"`
+
+	// KeepWeekly keeps the newest backup for each of the last N weeks
+	// Architecture: RetentionConfig.KeepWeekly
+	KeepWeekly int `yaml:"keep_weekly"`
+
+	// KeepMonthly keeps the newest backup for each of the last N months
+	// Architecture: RetentionConfig.KeepMonthly
+	KeepMonthly int `yaml:"keep_monthly"`
+
+	// MaxTotalBytes, if positive, evicts the oldest surviving backups
+	// (never the most recent one) until the total size of the pruned
+	// file's own surviving backups is at or below this limit, à la Docker's
+	// --keep-storage. Backups of other files under BackupDirPath are not
+	// counted toward the limit.
+	// Architecture: RetentionConfig.MaxTotalBytes
+	MaxTotalBytes int64 `yaml:"max_total_bytes"`
+
+	// MinFreeBytes, if positive, evicts the oldest surviving backups
+	// (never the most recent one) until the filesystem backing
+	// BackupDirPath reports at least this many bytes free. It is a
+	// best-effort check: if free space can't be determined (e.g. an
+	// in-memory afero.Fs in tests), it is skipped rather than failing
+	// the prune.
+	// Architecture: RetentionConfig.MinFreeBytes
+	MinFreeBytes int64 `yaml:"min_free_bytes"`
+
+	// PruneAfterBackup has CreateBackupWithTime run PruneBackups against
+	// this retention policy immediately after it creates a backup. A
+	// failure to prune is logged but does not fail the backup itself.
+	// Architecture: RetentionConfig.PruneAfterBackup
+	PruneAfterBackup bool `yaml:"prune_after_backup"`
+}
+
+// validate rejects malformed durations and nonsensical counts before a
+// RetentionConfig is used to prune anything.
+func (r RetentionConfig) validate() error {
+	if r.KeepWithin != "" {
+		if _, err := parseKeepWithin(r.KeepWithin); err != nil {
+			return fmt.Errorf("keep_within: %w", err)
+		}
+	}
+	for name, n := range map[string]int{
+		"keep_last":    r.KeepLast,
+		"keep_hourly":  r.KeepHourly,
+		"keep_daily":   r.KeepDaily,
+		"keep_weekly":  r.KeepWeekly,
+		"keep_monthly": r.KeepMonthly,
+	} {
+		if n < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", name, n)
+		}
+	}
+	if r.MaxTotalBytes < 0 {
+		return fmt.Errorf("max_total_bytes must not be negative, got %d", r.MaxTotalBytes)
+	}
+	if r.MinFreeBytes < 0 {
+		return fmt.Errorf("min_free_bytes must not be negative, got %d", r.MinFreeBytes)
+	}
+	return nil
+}
+
+// hasAnyPolicy reports whether any retention rule is configured.
+func (r RetentionConfig) hasAnyPolicy() bool {
+	return r.KeepLast > 0 || r.KeepWithin != "" || r.KeepHourly > 0 || r.KeepDaily > 0 ||
+		r.KeepWeekly > 0 || r.KeepMonthly > 0 || r.MaxTotalBytes > 0 || r.MinFreeBytes > 0
+}
+
+// parseKeepWithin parses a duration like "7d", "2w", or a plain
+// time.ParseDuration string such as "168h". time.ParseDuration doesn't
+// support day/week units, so "d"/"w" suffixes are expanded to hours first.
+func parseKeepWithin(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	unit := s[len(s)-1]
+	switch unit {
+	case 'd', 'w':
+		numPart := s[:len(s)-1]
+		n, err := strconv.ParseFloat(numPart, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		hoursPerUnit := 24.0
+		if unit == 'w' {
+			hoursPerUnit = 24.0 * 7.0
+		}
+		return time.Duration(n * hoursPerUnit * float64(time.Hour)), nil
+	default:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q: %w", s, err)
+		}
+		return d, nil
+	}
+}
+
+// PruneResult is the structured summary PruneBackups returns alongside its
+// *BackupError, so callers that want more than the error's message string
+// (tests, in particular) can assert on counts directly.
+// Architecture: Data Objects - PruneResult
+type PruneResult struct {
+	// Kept is the number of backups the retention policy kept.
+	Kept int
+	// Pruned is the number of backups removed, or that would be removed
+	// under dryRun.
+	Pruned int
+	// BytesFreed is the total size of the pruned backups. It is always 0
+	// under dryRun, since nothing is actually removed.
+	BytesFreed int64
+}
+
+// PruneBackups removes backups for filePath that fall outside the configured
+// retention policy. It never deletes the most recent backup, honors dryRun
+// by printing what would be deleted (using the same relative-path display
+// as CreateBackupWithTime) without touching the filesystem, and returns a
+// *PruneResult plus a *BackupError carrying cfg.StatusPruned on success so
+// callers can tell "nothing to prune" (0 files) apart from "pruned N files,
+// reclaiming M bytes" either from the message or from the result.
+// Architecture: Core Functions - Backup Management - PruneBackups
+func PruneBackups(cfg *Config, filePath string, dryRun bool) (*PruneResult, error) {
+	if !cfg.Retention.hasAnyPolicy() {
+		return nil, NewBackupError("no retention policy configured", cfg.StatusConfigError)
+	}
+
+	fsys := cfg.fs()
+
+	backups, err := ListBackupsFS(fsys, cfg.BackupDirPath, filePath)
+	if err != nil {
+		return nil, NewBackupError(fmt.Sprintf("failed to list existing backups: %v", err), cfg.StatusConfigError)
+	}
+	if len(backups) == 0 {
+		return &PruneResult{}, NewBackupError("no backups to prune", cfg.StatusPruned)
+	}
+
+	// ListBackups sorts most-recent first; keep that order throughout.
+	toKeep := selectBackupsToKeep(cfg.Retention, backups)
+	if cfg.Retention.MaxTotalBytes > 0 {
+		evictForMaxTotalBytes(fsys, backups, toKeep, cfg.Retention.MaxTotalBytes)
+	}
+	if cfg.Retention.MinFreeBytes > 0 {
+		evictForMinFreeBytes(fsys, cfg.BackupDirPath, backups, toKeep, cfg.Retention.MinFreeBytes)
+	}
+
+	result := &PruneResult{}
+	for _, backup := range backups {
+		if toKeep[backup.Path] {
+			result.Kept++
+			continue
+		}
+		relPath, err := filepath.Rel(".", backup.Path)
+		if err != nil {
+			relPath = backup.Path
+		}
+		if dryRun {
+			fmt.Printf("Would prune: %s\n", relPath)
+			result.Pruned++
+			continue
+		}
+		if info, err := fsys.Stat(backup.Path); err == nil {
+			result.BytesFreed += info.Size()
+		}
+		if err := fsys.Remove(backup.Path); err != nil {
+			return nil, NewBackupError(fmt.Sprintf("failed to prune %s: %v", relPath, err), cfg.StatusConfigError)
+		}
+		result.Pruned++
+	}
+
+	return result, NewBackupError(fmt.Sprintf("pruned %d backup(s), reclaiming %d byte(s)", result.Pruned, result.BytesFreed), cfg.StatusPruned)
+}
+
+// freeBytes returns the free space, in bytes, of the filesystem backing
+// dir. ok is false if it can't be determined, e.g. because dir doesn't
+// exist on the real filesystem (an in-memory afero.Fs in tests).
+func freeBytes(dir string) (free uint64, ok bool) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, false
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), true
+}
+
+// evictForMinFreeBytes drops backups from keep, oldest first, until
+// backupDir's filesystem is projected to have at least minFreeBytes free
+// (current free space plus the size of everything evicted so far). The
+// single most recent backup (backups[0]) is never evicted. It is a no-op
+// if free space can't be determined.
+func evictForMinFreeBytes(fsys afero.Fs, backupDir string, backups []Backup, keep map[string]bool, minFreeBytes int64) {
+	free, ok := freeBytes(backupDir)
+	if !ok || free >= uint64(minFreeBytes) {
+		return
+	}
+	deficit := minFreeBytes - int64(free)
+
+	var kept []Backup
+	for _, b := range backups {
+		if keep[b.Path] {
+			kept = append(kept, b)
+		}
+	}
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].CreationTime.Before(kept[j].CreationTime) })
+
+	remaining := len(kept)
+	for _, b := range kept {
+		if deficit <= 0 || remaining <= 1 {
+			break
+		}
+		if b.Path == backups[0].Path {
+			continue
+		}
+		info, err := fsys.Stat(b.Path)
+		if err != nil {
+			continue
+		}
+		delete(keep, b.Path)
+		deficit -= info.Size()
+		remaining--
+	}
+}
+
+// evictForMaxTotalBytes drops backups from keep, oldest first, until the
+// total size of the surviving kept backups is at or below maxBytes. The
+// single most recent backup (backups[0]) is never evicted, mirroring the
+// other retention rules.
+func evictForMaxTotalBytes(fsys afero.Fs, backups []Backup, keep map[string]bool, maxBytes int64) {
+	var kept []Backup
+	var total int64
+	for _, b := range backups {
+		if !keep[b.Path] {
+			continue
+		}
+		info, err := fsys.Stat(b.Path)
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+		kept = append(kept, b)
+	}
+	if total <= maxBytes {
+		return
+	}
+
+	// Oldest kept backups are evicted first.
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].CreationTime.Before(kept[j].CreationTime) })
+	remaining := len(kept)
+	for _, b := range kept {
+		if total <= maxBytes || remaining <= 1 {
+			break
+		}
+		if b.Path == backups[0].Path {
+			continue
+		}
+		info, err := fsys.Stat(b.Path)
+		if err != nil {
+			continue
+		}
+		delete(keep, b.Path)
+		total -= info.Size()
+		remaining--
+	}
+}
+
+// selectBackupsToKeep applies the retention policy to a most-recent-first
+// list of backups and returns the set of paths to keep.
+func selectBackupsToKeep(policy RetentionConfig, backups []Backup) map[string]bool {
+	keep := make(map[string]bool)
+
+	// The most recent backup is always kept, even if the policy would prune it.
+	keep[backups[0].Path] = true
+
+	if policy.KeepLast > 0 {
+		for i := 0; i < policy.KeepLast && i < len(backups); i++ {
+			keep[backups[i].Path] = true
+		}
+	}
+
+	if policy.KeepWithin != "" {
+		if d, err := parseKeepWithin(policy.KeepWithin); err == nil {
+			cutoff := timeNow().Add(-d)
+			for _, b := range backups {
+				if b.CreationTime.After(cutoff) {
+					keep[b.Path] = true
+				}
+			}
+		}
+	}
+
+	keepBucketed(backups, policy.KeepHourly, func(t time.Time) string { return t.Format("2006-01-02-15") }, keep)
+	keepBucketed(backups, policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }, keep)
+	keepBucketed(backups, policy.KeepWeekly, func(t time.Time) string { y, w := t.ISOWeek(); return fmt.Sprintf("%d-W%02d", y, w) }, keep)
+	keepBucketed(backups, policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }, keep)
+
+	return keep
+}
+
+// keepBucketed keeps the newest backup in each of the first `limit` distinct
+// buckets (as computed by bucketOf), walking backups newest-first.
+func keepBucketed(backups []Backup, limit int, bucketOf func(time.Time) string, keep map[string]bool) {
+	if limit <= 0 {
+		return
+	}
+	seen := make(map[string]bool)
+	for _, b := range backups {
+		if len(seen) >= limit {
+			break
+		}
+		bucket := bucketOf(b.CreationTime)
+		if seen[bucket] {
+			continue
+		}
+		seen[bucket] = true
+		keep[b.Path] = true
+	}
+}
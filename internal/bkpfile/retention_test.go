@@ -0,0 +1,321 @@
+package bkpfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+func TestLoadConfigRetention(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "bkpfile-retention-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name       string
+		yamlConfig string
+		wantErr    bool
+	}{
+		{
+			name: "valid day-based keep_within",
+			yamlConfig: `retention:
+  keep_within: 7d
+  keep_last: 3
+`,
+			wantErr: false,
+		},
+		{
+			name: "valid week-based keep_within",
+			yamlConfig: `retention:
+  keep_within: 2w
+`,
+			wantErr: false,
+		},
+		{
+			name: "malformed keep_within duration",
+			yamlConfig: `retention:
+  keep_within: not-a-duration
+`,
+			wantErr: true,
+		},
+		{
+			name: "negative keep_last is invalid",
+			yamlConfig: `retention:
+  keep_last: -1
+`,
+			wantErr: true,
+		},
+		{
+			name: "grandfather-father-son counters combine with keep_last",
+			yamlConfig: `retention:
+  keep_last: 5
+  keep_daily: 7
+  keep_weekly: 4
+  keep_monthly: 6
+`,
+			wantErr: false,
+		},
+		{
+			name: "min_free_bytes and prune_after_backup are accepted",
+			yamlConfig: `retention:
+  keep_last: 3
+  min_free_bytes: 1000000
+  prune_after_backup: true
+`,
+			wantErr: false,
+		},
+		{
+			name: "negative min_free_bytes is invalid",
+			yamlConfig: `retention:
+  min_free_bytes: -1
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			configPath := filepath.Join(tmpDir, ".bkpfile.yml")
+			if err := os.WriteFile(configPath, []byte(tt.yamlConfig), 0644); err != nil {
+				t.Fatalf("Failed to write config file: %v", err)
+			}
+			defer os.Remove(configPath)
+
+			_, _, err := LoadConfig(tmpDir)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// seedBackup writes a plain backup file for sourceFile under backupDir with
+// the given creation time, in the same layout ListBackupsFS expects.
+func seedBackup(t *testing.T, fsys afero.Fs, backupDir, sourceFile string, when time.Time) string {
+	t.Helper()
+	name := GenerateBackupName(filepath.Base(sourceFile), when.Format("2006-01-02-15-04"), "")
+	path := filepath.Join(backupDir, filepath.Dir(sourceFile), name)
+	if err := afero.WriteFile(fsys, path, []byte("x"), 0644); err != nil {
+		t.Fatalf("Failed to seed backup %s: %v", path, err)
+	}
+	if err := fsys.Chtimes(path, when, when); err != nil {
+		t.Fatalf("Failed to set mtime on %s: %v", path, err)
+	}
+	return path
+}
+
+func TestPruneBackupsKeepHourly(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	const sourceFile = "/src/file.txt"
+	backupDir := "/backups"
+
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	hour0 := seedBackup(t, fsys, backupDir, sourceFile, base)
+	hour1 := seedBackup(t, fsys, backupDir, sourceFile, base.Add(-1*time.Hour))
+	hour2 := seedBackup(t, fsys, backupDir, sourceFile, base.Add(-2*time.Hour))
+
+	cfg := DefaultConfig()
+	cfg.FS = fsys
+	cfg.BackupDirPath = backupDir
+	cfg.Retention = RetentionConfig{KeepHourly: 2}
+
+	if _, err := PruneBackups(cfg, sourceFile, false); err != nil {
+		if backupErr, ok := err.(*BackupError); !ok || backupErr.StatusCode != cfg.StatusPruned {
+			t.Fatalf("PruneBackups() error = %v", err)
+		}
+	}
+
+	for _, path := range []string{hour0, hour1} {
+		if exists, _ := afero.Exists(fsys, path); !exists {
+			t.Errorf("expected %s to survive pruning", path)
+		}
+	}
+	if exists, _ := afero.Exists(fsys, hour2); exists {
+		t.Errorf("expected %s outside the last 2 hourly buckets to be pruned", hour2)
+	}
+}
+
+func TestPruneBackupsMaxTotalBytes(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	const sourceFile = "/src/file.txt"
+	backupDir := "/backups"
+
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	var paths []string
+	for i := 0; i < 4; i++ {
+		when := base.Add(time.Duration(-i) * 24 * time.Hour)
+		name := GenerateBackupName(filepath.Base(sourceFile), when.Format("2006-01-02-15-04"), "")
+		path := filepath.Join(backupDir, filepath.Dir(sourceFile), name)
+		if err := afero.WriteFile(fsys, path, []byte("0123456789"), 0644); err != nil {
+			t.Fatalf("Failed to seed backup: %v", err)
+		}
+		if err := fsys.Chtimes(path, when, when); err != nil {
+			t.Fatalf("Failed to set mtime: %v", err)
+		}
+		paths = append(paths, path)
+	}
+
+	cfg := DefaultConfig()
+	cfg.FS = fsys
+	cfg.BackupDirPath = backupDir
+	// KeepLast keeps every backup; MaxTotalBytes should still evict down to
+	// 2 backups (20 bytes), oldest first.
+	cfg.Retention = RetentionConfig{KeepLast: 10, MaxTotalBytes: 20}
+
+	result, err := PruneBackups(cfg, sourceFile, false)
+	backupErr, ok := err.(*BackupError)
+	if !ok || backupErr.StatusCode != cfg.StatusPruned {
+		t.Fatalf("PruneBackups() error = %v", err)
+	}
+	if backupErr.Message != fmt.Sprintf("pruned %d backup(s), reclaiming %d byte(s)", 2, 20) {
+		t.Errorf("unexpected summary message: %q", backupErr.Message)
+	}
+	if result.Kept != 2 || result.Pruned != 2 || result.BytesFreed != 20 {
+		t.Errorf("PruneBackups() result = %+v, want {Kept:2 Pruned:2 BytesFreed:20}", result)
+	}
+
+	// The two newest backups (paths[0], paths[1]) should survive.
+	for _, path := range paths[:2] {
+		if exists, _ := afero.Exists(fsys, path); !exists {
+			t.Errorf("expected %s to survive max_total_bytes eviction", path)
+		}
+	}
+	for _, path := range paths[2:] {
+		if exists, _ := afero.Exists(fsys, path); exists {
+			t.Errorf("expected %s to be evicted by max_total_bytes", path)
+		}
+	}
+}
+
+func TestParseKeepWithin(t *testing.T) {
+	tests := []struct {
+		input   string
+		wantErr bool
+	}{
+		{"7d", false},
+		{"2w", false},
+		{"168h", false},
+		{"", true},
+		{"not-a-duration", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := parseKeepWithin(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseKeepWithin(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestRetentionConfigPrecedence is parallel to TestConfigurationIntegration:
+// a global and a local config each declare a retention: block, and the
+// local one's whole block must win over the global one, the same
+// first-file-wins precedence CreateBackupWithTime and friends already rely
+// on for backup_dir_path.
+func TestRetentionConfigPrecedence(t *testing.T) {
+	originalEnv := os.Getenv("BKPFILE_CONFIG")
+	defer func() {
+		if originalEnv != "" {
+			os.Setenv("BKPFILE_CONFIG", originalEnv)
+		} else {
+			os.Unsetenv("BKPFILE_CONFIG")
+		}
+	}()
+
+	tmpDir, err := os.MkdirTemp("", "bkpfile-retention-precedence-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(tmpDir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+	defer os.Chdir(originalWd)
+
+	globalConfig := `retention:
+  keep_last: 10
+  keep_daily: 7
+`
+	localConfig := `retention:
+  keep_last: 3
+`
+	if err := os.WriteFile("global.yml", []byte(globalConfig), 0644); err != nil {
+		t.Fatalf("Failed to create global config: %v", err)
+	}
+	if err := os.WriteFile("local.yml", []byte(localConfig), 0644); err != nil {
+		t.Fatalf("Failed to create local config: %v", err)
+	}
+
+	// BKPFILE_CONFIG is searched in order and the first file to set a given
+	// key wins (see TestConfigurationIntegration's "config precedence"
+	// case), so local.yml must be listed before global.yml for it to win.
+	os.Setenv("BKPFILE_CONFIG", "local.yml:global.yml")
+
+	cfg, _, err := LoadConfig(".")
+	if err != nil {
+		t.Fatalf("LoadConfig() unexpected error: %v", err)
+	}
+
+	// The local config declares the whole retention: block, so it wins
+	// outright over the global one rather than merging field-by-field
+	// within the block - the same whole-value precedence the "encryption"
+	// key gets in configFields.
+	if cfg.Retention.KeepLast != 3 {
+		t.Errorf("cfg.Retention.KeepLast = %d, want %d (local override)", cfg.Retention.KeepLast, 3)
+	}
+	if cfg.Retention.KeepDaily != 0 {
+		t.Errorf("cfg.Retention.KeepDaily = %d, want 0 (local config replaces the global retention: block entirely)", cfg.Retention.KeepDaily)
+	}
+}
+
+// TestCreateBackupWithTimePruneAfterBackup exercises retention.prune_after_backup:
+// CreateBackupWithTime should run PruneBackups against the same config right
+// after a successful backup, without the caller invoking it separately.
+func TestCreateBackupWithTimePruneAfterBackup(t *testing.T) {
+	fsys := afero.NewMemMapFs()
+	const sourceFile = "/src/file.txt"
+	backupDir := "/backups"
+
+	if err := afero.WriteFile(fsys, sourceFile, []byte("content"), 0644); err != nil {
+		t.Fatalf("Failed to write source file: %v", err)
+	}
+
+	base := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := seedBackup(t, fsys, backupDir, sourceFile, base.Add(-48*time.Hour))
+
+	cfg := DefaultConfig()
+	cfg.FS = fsys
+	cfg.BackupDirPath = backupDir
+	cfg.Retention = RetentionConfig{KeepLast: 1, PruneAfterBackup: true}
+
+	mockTime := func() time.Time { return base }
+	err := CreateBackupWithTime(cfg, sourceFile, "", false, mockTime)
+	if backupErr, ok := err.(*BackupError); !ok || backupErr.Message != "backup created successfully" {
+		t.Fatalf("CreateBackupWithTime() error = %v", err)
+	}
+
+	if exists, _ := afero.Exists(fsys, old); exists {
+		t.Errorf("expected %s to be pruned by prune_after_backup", old)
+	}
+
+	backups, err := ListBackupsFS(fsys, backupDir, sourceFile)
+	if err != nil {
+		t.Fatalf("ListBackupsFS() error: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("ListBackupsFS() returned %d backups, want 1 (only the new one, after prune_after_backup)", len(backups))
+	}
+}
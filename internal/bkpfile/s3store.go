@@ -0,0 +1,111 @@
+package bkpfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// s3BackupStore is the RemoteBackupFS backed by an S3 (or S3-compatible)
+// bucket, selected by a "s3://bucket/prefix" backup_url.
+// Architecture: Core Types - s3BackupStore
+type s3BackupStore struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+// newS3BackupStore builds an s3BackupStore from a parsed "s3://bucket/prefix"
+// URL. Credentials and region come from the standard AWS resolution chain
+// (environment, shared config file, instance role), the same as the AWS CLI.
+// Architecture: Core Functions - Backup Storage - newS3BackupStore
+func newS3BackupStore(u *url.URL) (*s3BackupStore, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("backup_url %q is missing an S3 bucket name", u.String())
+	}
+
+	awsCfg, err := awsconfig.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &s3BackupStore{
+		client: s3.NewFromConfig(awsCfg),
+		bucket: u.Host,
+		prefix: strings.Trim(u.Path, "/"),
+	}, nil
+}
+
+func (s *s3BackupStore) key(name string) string {
+	if s.prefix == "" {
+		return name
+	}
+	return s.prefix + "/" + name
+}
+
+func (s *s3BackupStore) Put(key string, data []byte) error {
+	_, err := s.client.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+		Body:   bytes.NewReader(data),
+	})
+	return err
+}
+
+func (s *s3BackupStore) Get(key string) ([]byte, error) {
+	out, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+func (s *s3BackupStore) Stat(key string) (int64, time.Time, error) {
+	out, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	var modTime time.Time
+	if out.LastModified != nil {
+		modTime = *out.LastModified
+	}
+	return aws.ToInt64(out.ContentLength), modTime, nil
+}
+
+func (s *s3BackupStore) List(prefix string) ([]string, error) {
+	out, err := s.client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.key(prefix)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0, len(out.Contents))
+	for _, obj := range out.Contents {
+		keys = append(keys, strings.TrimPrefix(aws.ToString(obj.Key), s.prefix+"/"))
+	}
+	return keys, nil
+}
+
+func (s *s3BackupStore) Delete(key string) error {
+	_, err := s.client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(key)),
+	})
+	return err
+}
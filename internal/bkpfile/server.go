@@ -0,0 +1,303 @@
+package bkpfile
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/spf13/afero"
+)
+
+// backupNamePattern matches the "filename-YYYY-MM-DD-HH-MM[=note]" shape
+// GenerateBackupName produces, after any archive/encryption suffix has
+// been stripped. The Server checks every path parameter against it before
+// touching the filesystem, so a request can't escape cfg.BackupDirPath
+// with a name like "../../etc/passwd".
+var backupNamePattern = regexp.MustCompile(`^[^/\\]+-\d{4}-\d{2}-\d{2}-\d{2}-\d{2}(=[^/\\]+)?$`)
+
+// validateBackupName reports whether name could plausibly be a backup
+// GenerateBackupName produced, rejecting anything containing a path
+// separator or parent-directory reference outright.
+// Architecture: Core Functions - HTTP Server - validateBackupName
+func validateBackupName(name string) error {
+	if name == "" || strings.ContainsAny(name, "/\\") || strings.Contains(name, "..") {
+		return fmt.Errorf("invalid backup name %q", name)
+	}
+	stripped := trimEncryptedSuffix(name)
+	for _, suffix := range []string{".tar.gz", ".tar", ".zip"} {
+		stripped = strings.TrimSuffix(stripped, suffix)
+	}
+	if !backupNamePattern.MatchString(stripped) {
+		return fmt.Errorf("backup name %q does not match the expected filename-YYYY-MM-DD-HH-MM[=note] shape", name)
+	}
+	return nil
+}
+
+// Server exposes bkpfile's backup operations over HTTP with bearer-token
+// authentication, so another machine or a CI job can create, list,
+// fetch, and delete backups without shelling out to the bkpfile binary
+// locally. It reuses cfg for both backup directory resolution (every
+// operation reads/writes under cfg.BackupDirPath on cfg.fs()) and status
+// codes (errors map their BackupError status code to an HTTP status).
+// Architecture: Data Objects - Server
+type Server struct {
+	cfg   *Config
+	token string
+
+	nameLocks   map[string]*sync.Mutex
+	nameLocksMu sync.Mutex
+}
+
+// NewServer builds a Server backed by cfg, requiring token as the bearer
+// credential on every request. token must not be empty: an empty token
+// would otherwise accept an empty Authorization header from anyone.
+// Architecture: Core Functions - HTTP Server - NewServer
+func NewServer(cfg *Config, token string) *Server {
+	return &Server{cfg: cfg, token: token, nameLocks: make(map[string]*sync.Mutex)}
+}
+
+// lockName acquires the per-filename lock for name, creating it on first
+// use, and returns a function that releases it. handleCreate holds this
+// lock across its stat/stage/backup/remove sequence so two concurrent
+// uploads of the same filename run one at a time instead of racing.
+func (s *Server) lockName(name string) func() {
+	s.nameLocksMu.Lock()
+	mu, ok := s.nameLocks[name]
+	if !ok {
+		mu = &sync.Mutex{}
+		s.nameLocks[name] = mu
+	}
+	s.nameLocksMu.Unlock()
+
+	mu.Lock()
+	return mu.Unlock
+}
+
+// ServeHTTP implements http.Handler, dispatching to the four backup
+// operations by method and path.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !s.authorize(w, r) {
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	switch {
+	case path == "backups" && r.Method == http.MethodPost:
+		s.handleCreate(w, r)
+	case path == "backups" && r.Method == http.MethodGet:
+		s.handleList(w, r)
+	case strings.HasPrefix(path, "backups/") && r.Method == http.MethodGet:
+		s.handleGet(w, r, strings.TrimPrefix(path, "backups/"))
+	case strings.HasPrefix(path, "backups/") && r.Method == http.MethodDelete:
+		s.handleDelete(w, r, strings.TrimPrefix(path, "backups/"))
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+// authorize checks the Authorization: Bearer <token> header, writing a
+// 401 and returning false if it's missing or doesn't match s.token.
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	const prefix = "Bearer "
+	auth := r.Header.Get("Authorization")
+	if s.token == "" || !strings.HasPrefix(auth, prefix) ||
+		subtle.ConstantTimeCompare([]byte(strings.TrimPrefix(auth, prefix)), []byte(s.token)) != 1 {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+// httpStatusFor maps a *BackupError's status code back to an HTTP status,
+// comparing against cfg's configured status codes rather than hardcoded
+// constants, since those are user-configurable.
+func (s *Server) httpStatusFor(err error) int {
+	backupErr, ok := err.(*BackupError)
+	if !ok {
+		return http.StatusInternalServerError
+	}
+	switch backupErr.StatusCode {
+	case s.cfg.StatusFileNotFound:
+		return http.StatusNotFound
+	case s.cfg.StatusPermissionDenied:
+		return http.StatusForbidden
+	case s.cfg.StatusInvalidFileType:
+		return http.StatusBadRequest
+	case s.cfg.StatusDiskFull:
+		return http.StatusInsufficientStorage
+	case s.cfg.StatusFileIsIdenticalToExistingBackup:
+		return http.StatusOK
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// writeJSON writes v as a JSON response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// handleCreate backs up the uploaded file from a multipart/form-data
+// "file" field, with an optional "note" field, via CreateBackup.
+//
+// CreateBackup groups a file's backups by its path relative to the
+// current directory, so the upload must be staged under its original
+// basename (not a temp name) to make a later GET /backups?source=<name>
+// find it. Concurrent requests can legitimately upload the same
+// filename at once (bkpfile serve has no lock-step with CreateBackups'
+// own worker pool), so staging and backing up that name is serialized
+// per filename by nameLocks: the second of two concurrent requests for
+// "notes.txt" waits for the first to finish removing its staged file
+// before it stages its own, instead of racing os.Create/os.Remove
+// against it.
+// POST /backups
+func (s *Server) handleCreate(w http.ResponseWriter, r *http.Request) {
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, fmt.Sprintf("missing file field: %v", err), http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	name := filepath.Base(header.Filename)
+	if name == "" || name != header.Filename || name == "." || name == ".." {
+		http.Error(w, "file name must be a bare filename with no path separators", http.StatusBadRequest)
+		return
+	}
+
+	unlock := s.lockName(name)
+	defer unlock()
+
+	if _, err := os.Stat(name); err == nil {
+		http.Error(w, fmt.Sprintf("a file named %q already exists; refusing to overwrite it for backup", name), http.StatusConflict)
+		return
+	}
+
+	dst, err := os.Create(name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to stage upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	if _, err := io.Copy(dst, file); err != nil {
+		dst.Close()
+		os.Remove(name)
+		http.Error(w, fmt.Sprintf("failed to stage upload: %v", err), http.StatusInternalServerError)
+		return
+	}
+	dst.Close()
+	defer os.Remove(name)
+
+	note := r.FormValue("note")
+	err = CreateBackup(s.cfg, name, note, false)
+	if backupErr, ok := err.(*BackupError); ok {
+		isSuccess := backupErr.StatusCode == s.cfg.StatusCreatedBackup ||
+			backupErr.StatusCode == s.cfg.StatusFileIsIdenticalToExistingBackup
+		if isSuccess {
+			writeJSON(w, http.StatusCreated, map[string]string{"status": "ok", "message": backupErr.Message})
+			return
+		}
+	}
+	if err != nil {
+		writeJSON(w, s.httpStatusFor(err), map[string]string{"status": "error", "message": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]string{"status": "ok"})
+}
+
+// handleList returns the backups recorded for ?source=<path>.
+// GET /backups?source=<path>
+func (s *Server) handleList(w http.ResponseWriter, r *http.Request) {
+	source := r.URL.Query().Get("source")
+	if source == "" {
+		http.Error(w, "source query parameter is required", http.StatusBadRequest)
+		return
+	}
+	backups, err := ListBackupsFS(s.cfg.fs(), s.cfg.BackupDirPath, source)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"status": "error", "message": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, backups)
+}
+
+// handleGet streams the backup named name for restore.
+// GET /backups/{name}
+func (s *Server) handleGet(w http.ResponseWriter, r *http.Request, name string) {
+	if err := validateBackupName(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	path, err := s.findBackup(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	f, err := s.cfg.fs().Open(path)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to open backup: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	io.Copy(w, f)
+}
+
+// handleDelete removes the backup named name and its sidecar metadata
+// file, if any.
+// DELETE /backups/{name}
+func (s *Server) handleDelete(w http.ResponseWriter, r *http.Request, name string) {
+	if err := validateBackupName(name); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	path, err := s.findBackup(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	if err := s.cfg.fs().Remove(path); err != nil {
+		http.Error(w, fmt.Sprintf("failed to delete backup: %v", err), http.StatusInternalServerError)
+		return
+	}
+	// Best-effort: a backup created before metadata.go landed has no
+	// sidecar, and that's not an error worth reporting.
+	s.cfg.fs().Remove(metaPath(path))
+
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// findBackup walks cfg.BackupDirPath looking for a regular file whose
+// base name is exactly name, returning its full path. name must already
+// have passed validateBackupName.
+func (s *Server) findBackup(name string) (string, error) {
+	var found string
+	err := afero.Walk(s.cfg.fs(), s.cfg.BackupDirPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || found != "" {
+			return err
+		}
+		if !info.IsDir() && info.Name() == name {
+			found = path
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to search backup directory: %w", err)
+	}
+	if found == "" {
+		return "", fmt.Errorf("no backup named %q", name)
+	}
+	return found, nil
+}
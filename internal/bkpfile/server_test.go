@@ -0,0 +1,213 @@
+package bkpfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+)
+
+func newMultipartUpload(t *testing.T, filename, content, note string) (*bytes.Buffer, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("CreateFormFile() error: %v", err)
+	}
+	if _, err := part.Write([]byte(content)); err != nil {
+		t.Fatalf("failed writing multipart body: %v", err)
+	}
+	if note != "" {
+		if err := writer.WriteField("note", note); err != nil {
+			t.Fatalf("WriteField() error: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	return &body, writer.FormDataContentType()
+}
+
+func TestServerRequiresBearerToken(t *testing.T) {
+	env, _ := newTestEnv(t)
+	cfg, _, err := env.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	srv := NewServer(cfg, "secret")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/backups?source=missing.txt")
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("GET /backups without a token = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestServerCreateListGetDelete(t *testing.T) {
+	env, _ := newTestEnv(t)
+	cfg, _, err := env.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	srv := NewServer(cfg, "secret")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	authedRequest := func(method, url string, body io.Reader, contentType string) *http.Response {
+		req, err := http.NewRequest(method, url, body)
+		if err != nil {
+			t.Fatalf("NewRequest() error: %v", err)
+		}
+		req.Header.Set("Authorization", "Bearer secret")
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s error: %v", method, url, err)
+		}
+		return resp
+	}
+
+	body, contentType := newMultipartUpload(t, "notes.txt", "hello from the server test", "from-http")
+	resp := authedRequest(http.MethodPost, ts.URL+"/backups", body, contentType)
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		data, _ := io.ReadAll(resp.Body)
+		t.Fatalf("POST /backups status = %d, body = %s", resp.StatusCode, data)
+	}
+
+	resp = authedRequest(http.MethodGet, ts.URL+"/backups?source=notes.txt", nil, "")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /backups status = %d", resp.StatusCode)
+	}
+	var backups []Backup
+	if err := json.NewDecoder(resp.Body).Decode(&backups); err != nil {
+		t.Fatalf("decoding backup list: %v", err)
+	}
+	if len(backups) != 1 {
+		t.Fatalf("GET /backups returned %d backups, want 1", len(backups))
+	}
+	name := backups[0].Name
+
+	resp = authedRequest(http.MethodGet, fmt.Sprintf("%s/backups/%s", ts.URL, name), nil, "")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /backups/%s status = %d", name, resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading backup stream: %v", err)
+	}
+	if string(data) != "hello from the server test" {
+		t.Errorf("GET /backups/%s body = %q, want original file content", name, data)
+	}
+
+	resp = authedRequest(http.MethodDelete, fmt.Sprintf("%s/backups/%s", ts.URL, name), nil, "")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("DELETE /backups/%s status = %d", name, resp.StatusCode)
+	}
+
+	backupsAfter, err := ListBackupsFS(cfg.fs(), cfg.BackupDirPath, "notes.txt")
+	if err != nil {
+		t.Fatalf("ListBackupsFS() error: %v", err)
+	}
+	if len(backupsAfter) != 0 {
+		t.Errorf("expected no backups left after delete, got %d", len(backupsAfter))
+	}
+}
+
+func TestServerCreateConcurrentSameFilename(t *testing.T) {
+	env, _ := newTestEnv(t)
+	cfg, _, err := env.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	srv := NewServer(cfg, "secret")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	const uploads = 8
+	var wg sync.WaitGroup
+	statuses := make([]int, uploads)
+	for i := 0; i < uploads; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body, contentType := newMultipartUpload(t, "concurrent.txt", fmt.Sprintf("payload %d", i), fmt.Sprintf("n%d", i))
+			req, err := http.NewRequest(http.MethodPost, ts.URL+"/backups", body)
+			if err != nil {
+				t.Errorf("NewRequest() error: %v", err)
+				return
+			}
+			req.Header.Set("Authorization", "Bearer secret")
+			req.Header.Set("Content-Type", contentType)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Errorf("POST /backups error: %v", err)
+				return
+			}
+			defer resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+	wg.Wait()
+
+	for i, status := range statuses {
+		if status != http.StatusCreated {
+			t.Errorf("upload %d status = %d, want %d", i, status, http.StatusCreated)
+		}
+	}
+
+	if _, err := os.Stat("concurrent.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected the staged file to be removed after every upload, stat err = %v", err)
+	}
+
+	backups, err := ListBackupsFS(cfg.fs(), cfg.BackupDirPath, "concurrent.txt")
+	if err != nil {
+		t.Fatalf("ListBackupsFS() error: %v", err)
+	}
+	if len(backups) != uploads {
+		t.Errorf("got %d backups after %d concurrent uploads, want %d", len(backups), uploads, uploads)
+	}
+}
+
+func TestServerRejectsPathTraversalInName(t *testing.T) {
+	env, _ := newTestEnv(t)
+	cfg, _, err := env.LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig() error: %v", err)
+	}
+
+	srv := NewServer(cfg, "secret")
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/backups/..%2F..%2Fetc%2Fpasswd", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest && resp.StatusCode != http.StatusNotFound {
+		t.Errorf("GET with a traversal name = %d, want 400 or 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,66 @@
+package bkpfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"bkpfile/internal/diag"
+)
+
+// TestEnv bundles a throwaway working directory with the helpers tests in
+// this file reach for most often: writing a config file, writing a backup
+// target, and loading the config LoadConfig would discover from cwd.
+type TestEnv struct {
+	T   *testing.T
+	Dir string
+}
+
+// newTestEnv creates a temp directory, chdirs into it, and registers
+// cleanup with t.Cleanup so the original working directory is restored
+// even if the test panics — t.TempDir() itself already guarantees the
+// directory is removed. The returned func is for tests that want to run
+// cleanup before the subtest ends instead of waiting for t.Cleanup.
+// Architecture: Core Functions - Test Support - newTestEnv
+func newTestEnv(t *testing.T) (*TestEnv, func()) {
+	t.Helper()
+
+	dir := t.TempDir()
+	originalWd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("Failed to get current directory: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Failed to change directory: %v", err)
+	}
+
+	restore := func() { os.Chdir(originalWd) }
+	t.Cleanup(restore)
+
+	return &TestEnv{T: t, Dir: dir}, restore
+}
+
+// WriteConfig writes yaml to name under env.Dir.
+func (env *TestEnv) WriteConfig(name, yaml string) {
+	env.T.Helper()
+	env.CreateFile(name, yaml)
+}
+
+// CreateFile writes content to name under env.Dir, creating any missing
+// parent directories.
+func (env *TestEnv) CreateFile(name, content string) {
+	env.T.Helper()
+	path := filepath.Join(env.Dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		env.T.Fatalf("Failed to create directory for %s: %v", name, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		env.T.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+// LoadConfig loads the config LoadConfig(".") would discover from env.Dir.
+func (env *TestEnv) LoadConfig() (*Config, diag.Diagnostics, error) {
+	env.T.Helper()
+	return LoadConfig(".")
+}
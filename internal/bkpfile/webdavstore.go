@@ -0,0 +1,198 @@
+package bkpfile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// webdavBackupStore is the RemoteBackupFS backed by a WebDAV server,
+// selected by a "webdav://host/path" (plain HTTP) or "webdavs://host/path"
+// (HTTPS) backup_url. It speaks WebDAV directly over net/http rather than
+// through a client library, since bkpfile only needs PUT/GET/PROPFIND/
+// DELETE/MKCOL on whole blobs.
+// Architecture: Core Types - webdavBackupStore
+type webdavBackupStore struct {
+	client  *http.Client
+	baseURL string
+	user    string
+	pass    string
+}
+
+// newWebDAVBackupStore builds a webdavBackupStore rooted at u.
+// Architecture: Core Functions - Backup Storage - newWebDAVBackupStore
+func newWebDAVBackupStore(u *url.URL) (*webdavBackupStore, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("backup_url %q is missing a WebDAV host", u.String())
+	}
+	scheme := "http"
+	if u.Scheme == "webdavs" {
+		scheme = "https"
+	}
+	password, _ := u.User.Password()
+	return &webdavBackupStore{
+		client:  &http.Client{},
+		baseURL: fmt.Sprintf("%s://%s%s", scheme, u.Host, strings.TrimSuffix(u.Path, "/")),
+		user:    u.User.Username(),
+		pass:    password,
+	}, nil
+}
+
+func (s *webdavBackupStore) url(key string) string {
+	return s.baseURL + "/" + key
+}
+
+func (s *webdavBackupStore) do(req *http.Request) (*http.Response, error) {
+	if s.user != "" {
+		req.SetBasicAuth(s.user, s.pass)
+	}
+	return s.client.Do(req)
+}
+
+func (s *webdavBackupStore) Put(key string, data []byte) error {
+	if err := s.mkdirAll(path.Dir(key)); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPut, s.url(key), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("PUT %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+func (s *webdavBackupStore) Get(key string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, s.url(key), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("GET %s: %s", key, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (s *webdavBackupStore) Stat(key string) (int64, time.Time, error) {
+	req, err := http.NewRequest(http.MethodHead, s.url(key), nil)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return 0, time.Time{}, fmt.Errorf("HEAD %s: %s", key, resp.Status)
+	}
+	size, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	modTime, _ := http.ParseTime(resp.Header.Get("Last-Modified"))
+	return size, modTime, nil
+}
+
+// webdavMultistatus is the minimal subset of a WebDAV PROPFIND response
+// List needs: the href of every member of a collection.
+type webdavMultistatus struct {
+	Responses []struct {
+		Href string `xml:"href"`
+	} `xml:"response"`
+}
+
+func (s *webdavBackupStore) List(prefix string) ([]string, error) {
+	req, err := http.NewRequest("PROPFIND", s.url(prefix), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Depth", "1")
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("PROPFIND %s: %s", prefix, resp.Status)
+	}
+
+	var ms webdavMultistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("failed to parse PROPFIND response for %s: %w", prefix, err)
+	}
+
+	collection, err := url.Parse(s.url(prefix))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		if strings.TrimSuffix(href.Path, "/") == strings.TrimSuffix(collection.Path, "/") {
+			continue // the collection itself, not a member
+		}
+		keys = append(keys, path.Join(prefix, path.Base(strings.TrimSuffix(href.Path, "/"))))
+	}
+	return keys, nil
+}
+
+func (s *webdavBackupStore) Delete(key string) error {
+	req, err := http.NewRequest(http.MethodDelete, s.url(key), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("DELETE %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+func (s *webdavBackupStore) mkdirAll(dir string) error {
+	if dir == "" || dir == "." {
+		return nil
+	}
+	req, err := http.NewRequest("MKCOL", s.baseURL+"/"+dir, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 201 Created and 405 Method Not Allowed (the collection already
+	// exists) are both fine; anything else is a real failure.
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusMethodNotAllowed {
+		return fmt.Errorf("MKCOL %s: %s", dir, resp.Status)
+	}
+	return nil
+}
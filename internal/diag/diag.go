@@ -0,0 +1,102 @@
+// Package diag provides a small diagnostics type for surfacing non-fatal
+// configuration problems (unknown keys, missing files, deprecated fields)
+// as warnings alongside, rather than instead of, fatal errors.
+package diag
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity classifies how serious a Diagnostic is.
+type Severity int
+
+const (
+	// Error indicates the diagnostic should stop further processing
+	Error Severity = iota
+	// Warning indicates a non-fatal problem the caller should surface to the user
+	Warning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case Error:
+		return "Error"
+	case Warning:
+		return "Warning"
+	default:
+		return "Unknown"
+	}
+}
+
+// Diagnostic is a single configuration problem, fatal or not.
+type Diagnostic struct {
+	// Severity distinguishes a fatal problem from a warning
+	Severity Severity
+
+	// Summary is a short, single-line description of the problem
+	Summary string
+
+	// Detail is optional additional context (e.g. the underlying error text)
+	Detail string
+
+	// Path identifies the config file or field the diagnostic refers to
+	Path string
+}
+
+// Diagnostics is an ordered collection of Diagnostic values.
+type Diagnostics []Diagnostic
+
+// Errorf builds a single-element Diagnostics with Error severity.
+func Errorf(path, format string, args ...interface{}) Diagnostics {
+	return Diagnostics{{Severity: Error, Summary: fmt.Sprintf(format, args...), Path: path}}
+}
+
+// Warningf builds a single-element Diagnostics with Warning severity.
+func Warningf(path, format string, args ...interface{}) Diagnostics {
+	return Diagnostics{{Severity: Warning, Summary: fmt.Sprintf(format, args...), Path: path}}
+}
+
+// HasError reports whether any diagnostic in the collection is an Error.
+func (ds Diagnostics) HasError() bool {
+	for _, d := range ds {
+		if d.Severity == Error {
+			return true
+		}
+	}
+	return false
+}
+
+// Error renders every Error-severity diagnostic as a single multi-line
+// string, satisfying the error interface. It returns an empty string when
+// there are no errors, so callers should guard with HasError first.
+func (ds Diagnostics) Error() string {
+	var lines []string
+	for _, d := range ds {
+		if d.Severity != Error {
+			continue
+		}
+		if d.Path != "" {
+			lines = append(lines, d.Path+": "+d.Summary)
+		} else {
+			lines = append(lines, d.Summary)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Warnings returns only the Warning-severity diagnostics, in order.
+func (ds Diagnostics) Warnings() Diagnostics {
+	var out Diagnostics
+	for _, d := range ds {
+		if d.Severity == Warning {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// Extend appends other to ds and returns the combined collection.
+func (ds Diagnostics) Extend(other Diagnostics) Diagnostics {
+	return append(ds, other...)
+}